@@ -0,0 +1,347 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// accountDeletionGracePeriod is how long a user has to change their mind
+// after asking for their account to be deleted.
+const accountDeletionGracePeriod = 30 * 24 * time.Hour
+
+func (cfg *apiConfig) handlerAccountDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err := cfg.checkPassword(r.Context(), cfg.clientIP(r), user, params.Password); err != nil {
+		respondLoginError(w, err)
+		return
+	}
+
+	// Asking again doesn't push the deletion further back.
+	if !user.DeletionRequestedAt.Valid {
+		user, err = cfg.db.RequestUserDeletion(r.Context(), database.RequestUserDeletionParams{
+			ID:                  userID,
+			DeletionRequestedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]any{
+		"deletion_scheduled_at": user.DeletionRequestedAt.Time.Add(accountDeletionGracePeriod),
+	})
+}
+
+func (cfg *apiConfig) handlerAccountRestore(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	restored, err := cfg.db.CancelUserDeletion(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if restored == 0 {
+		respondWithError(w, http.StatusNotFound, "Account is not scheduled for deletion")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// purgeDeletedAccounts hard deletes the accounts whose grace period is
+// over. Everything they own goes with them through ON DELETE CASCADE,
+// except for media blobs, which are removed from storage here.
+func (cfg *apiConfig) purgeDeletedAccounts(ctx context.Context) {
+	userIDs, err := cfg.db.GetUsersDueForDeletion(ctx, time.Now().UTC().Add(-accountDeletionGracePeriod))
+	if err != nil {
+		log.Printf("scheduler: finding accounts to delete: %s", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		files, err := cfg.db.GetMediaFilesByUser(ctx, userID)
+		if err != nil {
+			log.Printf("scheduler: listing media of user %s: %s", userID, err)
+			continue
+		}
+		if err := cfg.db.DeleteUser(ctx, userID); err != nil {
+			log.Printf("scheduler: deleting user %s: %s", userID, err)
+			continue
+		}
+		for _, file := range files {
+			for _, key := range []string{file.StorageKey, file.ThumbnailKey} {
+				if err := cfg.media.Delete(ctx, key); err != nil {
+					log.Printf("deleting media %s: %s", key, err)
+				}
+			}
+		}
+		log.Printf("scheduler: deleted user %s", userID)
+	}
+}
+
+// AccountExport is everything stored about a user, as written to
+// data.json in their export archive.
+type AccountExport struct {
+	ExportedAt time.Time `json:"exported_at"`
+	User       struct {
+		ID                  uuid.UUID  `json:"id"`
+		CreatedAt           time.Time  `json:"created_at"`
+		UpdatedAt           time.Time  `json:"updated_at"`
+		Email               string     `json:"email"`
+		EmailVerifiedAt     *time.Time `json:"email_verified_at"`
+		IsChirpyRed         bool       `json:"is_chirpy_red"`
+		Protected           bool       `json:"protected"`
+		Role                string     `json:"role"`
+		DeletionRequestedAt *time.Time `json:"deletion_requested_at"`
+	} `json:"user"`
+	// TwoFactor is only the state of two-factor authentication, never the
+	// TOTP secret or recovery codes.
+	TwoFactor struct {
+		TOTPEnabled       bool  `json:"totp_enabled"`
+		RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+	} `json:"two_factor"`
+	Chirps              []database.Chirp              `json:"chirps"`
+	Media               []database.MediaFile          `json:"media"`
+	Drafts              []database.Draft              `json:"drafts"`
+	PinnedChirpIDs      []uuid.UUID                   `json:"pinned_chirp_ids"`
+	BookmarkCollections []database.BookmarkCollection `json:"bookmark_collections"`
+	Bookmarks           []database.Bookmark           `json:"bookmarks"`
+	Follows             []database.Follow             `json:"follows"`
+	Lists               []List                        `json:"lists"`
+	PollVotes           []database.PollVote           `json:"poll_votes"`
+	RoleChanges         []database.RoleChange         `json:"role_changes"`
+	Passkeys            []Passkey                     `json:"passkeys"`
+	Sessions            []Session                     `json:"sessions"`
+	APITokens           []APIToken                    `json:"api_tokens"`
+	OAuthClients        []OAuthClient                 `json:"oauth_clients"`
+	OAuthGrants         []OAuthGrant                  `json:"oauth_grants"`
+	ExternalIdentities  []ExternalIdentity            `json:"external_identities"`
+}
+
+func (cfg *apiConfig) accountExport(ctx context.Context, user database.User) (AccountExport, error) {
+	export := AccountExport{ExportedAt: time.Now().UTC()}
+	export.User.ID = user.ID
+	export.User.CreatedAt = user.CreatedAt
+	export.User.UpdatedAt = user.UpdatedAt
+	export.User.Email = user.Email
+	if user.EmailVerifiedAt.Valid {
+		export.User.EmailVerifiedAt = &user.EmailVerifiedAt.Time
+	}
+	export.User.IsChirpyRed = user.IsChirpyRed
+	export.User.Protected = user.Protected
+	export.User.Role = user.Role
+	if user.DeletionRequestedAt.Valid {
+		export.User.DeletionRequestedAt = &user.DeletionRequestedAt.Time
+	}
+
+	export.TwoFactor.TOTPEnabled = user.TotpEnabled
+
+	var err error
+	if export.TwoFactor.RecoveryCodesLeft, err = cfg.db.CountUnusedRecoveryCodes(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.Chirps, err = cfg.db.GetOwnChirps(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.Media, err = cfg.db.GetMediaFilesByUser(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.Drafts, err = cfg.db.GetDraftsByUser(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.PinnedChirpIDs, err = cfg.db.GetPinnedChirpIDs(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.BookmarkCollections, err = cfg.db.GetBookmarkCollectionsByUser(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.Bookmarks, err = cfg.db.GetBookmarksByUser(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.Follows, err = cfg.db.GetFollowsByUser(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.PollVotes, err = cfg.db.GetPollVotesByUser(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+	if export.RoleChanges, err = cfg.db.GetRoleChangesByUser(ctx, uuid.NullUUID{UUID: user.ID, Valid: true}); err != nil {
+		return AccountExport{}, err
+	}
+
+	passkeys, err := cfg.db.GetPasskeysByUser(ctx, user.ID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+	for _, passkey := range passkeys {
+		export.Passkeys = append(export.Passkeys, passkeyResponse(passkey))
+	}
+
+	sessions, err := cfg.db.GetActiveSessions(ctx, database.GetActiveSessionsParams{
+		UserID: user.ID,
+		Now:    time.Now().UTC(),
+	})
+	if err != nil {
+		return AccountExport{}, err
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, sessionResponse(session))
+	}
+
+	apiTokens, err := cfg.db.GetAPITokens(ctx, user.ID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+	for _, token := range apiTokens {
+		export.APITokens = append(export.APITokens, apiTokenResponse(token))
+	}
+
+	oauthClients, err := cfg.db.GetOAuthClientsByOwner(ctx, user.ID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+	for _, client := range oauthClients {
+		export.OAuthClients = append(export.OAuthClients, oauthClientResponse(client))
+	}
+	if export.OAuthGrants, err = cfg.oauthGrants(ctx, user.ID); err != nil {
+		return AccountExport{}, err
+	}
+
+	identities, err := cfg.db.GetExternalIdentities(ctx, user.ID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+	for _, identity := range identities {
+		export.ExternalIdentities = append(export.ExternalIdentities, externalIdentityResponse(identity))
+	}
+
+	lists, err := cfg.db.GetListsByUser(ctx, user.ID)
+	if err != nil {
+		return AccountExport{}, err
+	}
+	for _, list := range lists {
+		response, err := cfg.listResponse(ctx, list)
+		if err != nil {
+			return AccountExport{}, err
+		}
+		export.Lists = append(export.Lists, response)
+	}
+
+	return export, nil
+}
+
+func mediaExtension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+// handlerAccountExport streams a zip archive with data.json and the
+// original of every uploaded media file under media/.
+func (cfg *apiConfig) handlerAccountExport(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	export, err := cfg.accountExport(r.Context(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, export.ExportedAt.Format("20060102")))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// The status line is already sent, so from here on failures can only
+	// be logged; the client is left with a truncated archive.
+	archive := zip.NewWriter(w)
+	defer archive.Close()
+
+	file, err := archive.Create("data.json")
+	if err != nil {
+		log.Printf("exporting user %s: %s", userID, err)
+		return
+	}
+	if _, err := file.Write(data); err != nil {
+		log.Printf("exporting user %s: %s", userID, err)
+		return
+	}
+
+	for _, mediaFile := range export.Media {
+		blob, err := cfg.media.Get(r.Context(), mediaFile.StorageKey)
+		if err != nil {
+			log.Printf("exporting user %s: media %s: %s", userID, mediaFile.ID, err)
+			continue
+		}
+		err = copyToArchive(archive, "media/"+mediaFile.ID.String()+mediaExtension(mediaFile.ContentType), blob)
+		blob.Close()
+		if err != nil {
+			log.Printf("exporting user %s: media %s: %s", userID, mediaFile.ID, err)
+			return
+		}
+	}
+}
+
+func copyToArchive(archive *zip.Writer, name string, src io.Reader) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, src)
+	return err
+}
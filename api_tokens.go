@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	scopeChirpsRead   = "chirps:read"
+	scopeChirpsWrite  = "chirps:write"
+	scopeProfileWrite = "profile:write"
+)
+
+var apiTokenScopes = []string{scopeChirpsRead, scopeChirpsWrite, scopeProfileWrite}
+
+const maxAPITokenNameLen = 50
+
+type APIToken struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Token is only set when the token is created; it can't be shown again.
+	Token string `json:"token,omitempty"`
+}
+
+func apiTokenResponse(token database.ApiToken) APIToken {
+	response := APIToken{
+		ID:        token.ID,
+		CreatedAt: token.CreatedAt,
+		Name:      token.Name,
+		Scopes:    strings.Fields(token.Scopes),
+	}
+	if token.ExpiresAt.Valid {
+		response.ExpiresAt = &token.ExpiresAt.Time
+	}
+	if token.LastUsedAt.Valid {
+		response.LastUsedAt = &token.LastUsedAt.Time
+	}
+	return response
+}
+
+type (
+	apiTokenUserKey struct{}
+	routeScopeKey   struct{}
+)
+
+// acceptAPITokens lets requests to next authenticate with a personal access
+// token in an "Authorization: ApiKey" header, or with an OAuth client's
+// access token, as long as the token carries scope. The token's owner is
+// then what authenticatedUser returns. Routes that aren't wrapped only
+// accept first-party access tokens, so a leaked personal access token or a
+// third-party app can't be used to manage the account itself.
+func (cfg *apiConfig) acceptAPITokens(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "ApiKey ") {
+			ctx := context.WithValue(r.Context(), routeScopeKey{}, scope)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		token, err := auth.GetAPIKey(r.Header)
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+		apiToken, err := cfg.db.GetAPITokenByHash(r.Context(), auth.HashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondUnauthorized(w, errors.New("Invalid API token"))
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if apiToken.ExpiresAt.Valid && !apiToken.ExpiresAt.Time.After(time.Now().UTC()) {
+			respondUnauthorized(w, errors.New("API token expired"))
+			return
+		}
+		if !slices.Contains(strings.Fields(apiToken.Scopes), scope) {
+			respondWithError(w, http.StatusForbidden, "API token lacks the "+scope+" scope")
+			return
+		}
+
+		if err := cfg.db.TouchAPIToken(r.Context(), apiToken.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), apiTokenUserKey{}, apiToken.UserID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (cfg *apiConfig) handlerAPITokenCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	type parameters struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > maxAPITokenNameLen {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Token names must be between 1 and %d characters", maxAPITokenNameLen))
+		return
+	}
+	if len(params.Scopes) == 0 {
+		respondWithError(w, http.StatusBadRequest, "A token needs at least one scope")
+		return
+	}
+	scopes := []string{}
+	for _, scope := range params.Scopes {
+		if !slices.Contains(apiTokenScopes, scope) {
+			respondWithError(w, http.StatusBadRequest, "Unknown scope "+scope+", expected one of "+strings.Join(apiTokenScopes, ", "))
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			respondWithError(w, http.StatusBadRequest, "Token expiry must be in the future")
+			return
+		}
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+
+	token := auth.MakeAPIToken()
+	apiToken, err := cfg.db.CreateAPIToken(r.Context(), database.CreateAPITokenParams{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: auth.HashToken(token),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := apiTokenResponse(apiToken)
+	response.Token = token
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (cfg *apiConfig) handlerAPITokensList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	tokens, err := cfg.db.GetAPITokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]APIToken, len(tokens))
+	for i, token := range tokens {
+		response[i] = apiTokenResponse(token)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerAPITokenRevoke(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	deleted, err := cfg.db.DeleteAPIToken(r.Context(), database.DeleteAPITokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Token not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
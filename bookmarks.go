@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Bookmark struct {
+	CollectionID *uuid.UUID `json:"collection_id"`
+	SavedAt      time.Time  `json:"saved_at"`
+	Chirp        Chirp      `json:"chirp"`
+}
+
+// bookmarksResponse resolves the bookmarked chirps, silently dropping the
+// ones the user can no longer see. Deleted chirps are already gone through
+// the ON DELETE CASCADE on bookmarks.
+func (cfg *apiConfig) bookmarksResponse(ctx context.Context, bookmarks []database.Bookmark, userID uuid.UUID) ([]Bookmark, error) {
+	ids := make([]uuid.UUID, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		ids[i] = bookmark.ChirpID
+	}
+
+	chirps, err := cfg.db.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := cfg.visibleChirps(ctx, chirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := cfg.chirpsResponse(ctx, visible, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]Chirp, len(responses))
+	for _, chirp := range responses {
+		byID[chirp.ID] = chirp
+	}
+
+	response := []Bookmark{}
+	for _, bookmark := range bookmarks {
+		chirp, ok := byID[bookmark.ChirpID]
+		if !ok {
+			continue
+		}
+		var collectionID *uuid.UUID
+		if bookmark.CollectionID.Valid {
+			collectionID = &bookmark.CollectionID.UUID
+		}
+		response = append(response, Bookmark{
+			CollectionID: collectionID,
+			SavedAt:      bookmark.CreatedAt,
+			Chirp:        chirp,
+		})
+	}
+
+	return response, nil
+}
+
+// collectionParam reads the optional collection_id query parameter and
+// checks that the collection belongs to userID.
+func (cfg *apiConfig) collectionParam(r *http.Request, userID uuid.UUID) (uuid.NullUUID, int, error) {
+	param := r.URL.Query().Get("collection_id")
+	if param == "" {
+		return uuid.NullUUID{}, 0, nil
+	}
+	return cfg.ownCollection(r.Context(), param, userID)
+}
+
+func (cfg *apiConfig) ownCollection(ctx context.Context, id string, userID uuid.UUID) (uuid.NullUUID, int, error) {
+	collectionID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.NullUUID{}, http.StatusBadRequest, errors.New("Invalid UUID:" + err.Error())
+	}
+
+	_, err = cfg.db.GetBookmarkCollection(ctx, database.GetBookmarkCollectionParams{
+		ID:     collectionID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.NullUUID{}, http.StatusNotFound, errors.New("Collection not found")
+	}
+	if err != nil {
+		return uuid.NullUUID{}, http.StatusInternalServerError, err
+	}
+
+	return uuid.NullUUID{UUID: collectionID, Valid: true}, 0, nil
+}
+
+func (cfg *apiConfig) handlerBookmarksList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	collectionID, status, err := cfg.collectionParam(r, userID)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	var bookmarks []database.Bookmark
+	if collectionID.Valid {
+		bookmarks, err = cfg.db.GetBookmarksInCollection(r.Context(), database.GetBookmarksInCollectionParams{
+			UserID:       userID,
+			CollectionID: collectionID,
+		})
+	} else {
+		bookmarks, err = cfg.db.GetBookmarksByUser(r.Context(), userID)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response, err := cfg.bookmarksResponse(r.Context(), bookmarks, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerBookmarkCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	type parameters struct {
+		ChirpID      uuid.UUID `json:"chirp_id"`
+		CollectionID string    `json:"collection_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), params.ChirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	visible, err := cfg.chirpVisibleTo(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !visible {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	var collectionID uuid.NullUUID
+	if params.CollectionID != "" {
+		var status int
+		collectionID, status, err = cfg.ownCollection(r.Context(), params.CollectionID, userID)
+		if err != nil {
+			respondWithError(w, status, err.Error())
+			return
+		}
+	}
+
+	created, err := cfg.db.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+		UserID:       userID,
+		ChirpID:      chirp.ID,
+		CollectionID: collectionID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if created == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (cfg *apiConfig) handlerBookmarkDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	collectionID, status, err := cfg.collectionParam(r, userID)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	var deleted int64
+	if collectionID.Valid {
+		deleted, err = cfg.db.DeleteBookmarkFromCollection(r.Context(), database.DeleteBookmarkFromCollectionParams{
+			UserID:       userID,
+			ChirpID:      chirpID,
+			CollectionID: collectionID,
+		})
+	} else {
+		deleted, err = cfg.db.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+			UserID:  userID,
+			ChirpID: chirpID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Bookmark not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeCollectionName(r *http.Request) (string, error) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		return "", errors.New("Invalid JSON:" + err.Error())
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > 50 {
+		return "", errors.New("Collection name must be between 1 and 50 characters")
+	}
+	return name, nil
+}
+
+func (cfg *apiConfig) handlerBookmarkCollectionsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	collections, err := cfg.db.GetBookmarkCollectionsByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if collections == nil {
+		collections = []database.BookmarkCollection{}
+	}
+
+	respondWithJSON(w, http.StatusOK, collections)
+}
+
+func (cfg *apiConfig) handlerBookmarkCollectionCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	name, err := decodeCollectionName(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	collection, err := cfg.db.CreateBookmarkCollection(r.Context(), database.CreateBookmarkCollectionParams{
+		UserID: userID,
+		Name:   name,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "A collection with that name already exists")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, collection)
+}
+
+func (cfg *apiConfig) handlerBookmarkCollectionRename(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	collectionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	name, err := decodeCollectionName(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	collection, err := cfg.db.RenameBookmarkCollection(r.Context(), database.RenameBookmarkCollectionParams{
+		Name:   name,
+		ID:     collectionID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "A collection with that name already exists")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, collection)
+}
+
+func (cfg *apiConfig) handlerBookmarkCollectionDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	collectionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	deleted, err := cfg.db.DeleteBookmarkCollection(r.Context(), database.DeleteBookmarkCollectionParams{
+		ID:     collectionID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
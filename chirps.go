@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Chirp is the API representation of a chirp, with everything stored
+// alongside it in other tables.
+type Chirp struct {
+	database.Chirp
+	Media    []Attachment `json:"media"`
+	Poll     *Poll        `json:"poll"`
+	Mentions []uuid.UUID  `json:"mentions"`
+	// Pinned is only set when listing an author's chirps with their pins.
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+const maxChirpMentions = 10
+
+const (
+	visibilityPublic    = "public"
+	visibilityFollowers = "followers"
+	visibilityMentioned = "mentioned"
+)
+
+func validVisibility(visibility string) bool {
+	switch visibility {
+	case visibilityPublic, visibilityFollowers, visibilityMentioned:
+		return true
+	}
+	return false
+}
+
+// visibleChirps filters chirps down to the ones viewer may read, keeping
+// their order. viewer is uuid.Nil for anonymous requests. Authors can
+// always see their own chirps, even while they are held or scheduled.
+// Followers-only chirps are also shown to the users they mention, like
+// mentioned-only ones. Every chirp from a protected account is restricted
+// to its approved followers, whatever its visibility.
+func (cfg *apiConfig) visibleChirps(ctx context.Context, chirps []database.Chirp, viewer uuid.UUID) ([]database.Chirp, error) {
+	var restrictedIDs, authorIDs []uuid.UUID
+	for _, chirp := range chirps {
+		if chirp.UserID != viewer {
+			restrictedIDs = append(restrictedIDs, chirp.ID)
+			authorIDs = append(authorIDs, chirp.UserID)
+		}
+	}
+
+	protected := map[uuid.UUID]bool{}
+	followed := map[uuid.UUID]bool{}
+	mentioned := map[uuid.UUID]bool{}
+	if len(authorIDs) > 0 {
+		protectedIDs, err := cfg.db.GetProtectedAmong(ctx, authorIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range protectedIDs {
+			protected[id] = true
+		}
+	}
+	if viewer != uuid.Nil && len(restrictedIDs) > 0 {
+		followedIDs, err := cfg.db.GetFollowedAmong(ctx, database.GetFollowedAmongParams{
+			FollowerID: viewer,
+			UserIds:    authorIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range followedIDs {
+			followed[id] = true
+		}
+
+		mentionedIDs, err := cfg.db.GetMentionedAmong(ctx, database.GetMentionedAmongParams{
+			UserID:   viewer,
+			ChirpIds: restrictedIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range mentionedIDs {
+			mentioned[id] = true
+		}
+	}
+
+	visible := []database.Chirp{}
+	for _, chirp := range chirps {
+		if chirp.UserID != viewer {
+			if chirp.Held || !chirp.Published {
+				continue
+			}
+			if protected[chirp.UserID] && !followed[chirp.UserID] {
+				continue
+			}
+			if chirp.Visibility == visibilityFollowers && !followed[chirp.UserID] && !mentioned[chirp.ID] {
+				continue
+			}
+			if chirp.Visibility == visibilityMentioned && !mentioned[chirp.ID] {
+				continue
+			}
+		}
+		visible = append(visible, chirp)
+	}
+
+	return visible, nil
+}
+
+func (cfg *apiConfig) chirpVisibleTo(ctx context.Context, chirp database.Chirp, viewer uuid.UUID) (bool, error) {
+	visible, err := cfg.visibleChirps(ctx, []database.Chirp{chirp}, viewer)
+	if err != nil {
+		return false, err
+	}
+	return len(visible) == 1, nil
+}
+
+// chirpResponse builds the response for a single chirp as seen by viewer,
+// which is uuid.Nil for anonymous requests.
+func (cfg *apiConfig) chirpResponse(ctx context.Context, chirp database.Chirp, viewer uuid.UUID) (Chirp, error) {
+	chirps, err := cfg.chirpsResponse(ctx, []database.Chirp{chirp}, viewer)
+	if err != nil {
+		return Chirp{}, err
+	}
+	return chirps[0], nil
+}
+
+func (cfg *apiConfig) chirpsResponse(ctx context.Context, chirps []database.Chirp, viewer uuid.UUID) ([]Chirp, error) {
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	files, err := cfg.db.GetMediaFilesForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	attachments := map[uuid.UUID][]Attachment{}
+	for _, file := range files {
+		attachments[file.ChirpID.UUID] = append(attachments[file.ChirpID.UUID], attachmentResponse(file))
+	}
+
+	polls, err := cfg.pollsForChirps(ctx, ids, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	mentionRows, err := cfg.db.GetMentionsForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mentions := map[uuid.UUID][]uuid.UUID{}
+	for _, mention := range mentionRows {
+		mentions[mention.ChirpID] = append(mentions[mention.ChirpID], mention.UserID)
+	}
+
+	response := make([]Chirp, len(chirps))
+	for i, chirp := range chirps {
+		response[i] = Chirp{
+			Chirp:    chirp,
+			Media:    attachments[chirp.ID],
+			Poll:     polls[chirp.ID],
+			Mentions: mentions[chirp.ID],
+		}
+		if response[i].Media == nil {
+			response[i].Media = []Attachment{}
+		}
+		if response[i].Mentions == nil {
+			response[i].Mentions = []uuid.UUID{}
+		}
+	}
+
+	return response, nil
+}
+
+// deleteChirp deletes a chirp along with its media blobs. Pins, bookmarks,
+// polls and media rows go with the chirp through their ON DELETE CASCADE
+// foreign keys, but nothing would point at the blobs afterwards.
+func (cfg *apiConfig) deleteChirp(ctx context.Context, chirpID uuid.UUID) error {
+	files, err := cfg.db.GetMediaFilesForChirps(ctx, []uuid.UUID{chirpID})
+	if err != nil {
+		return err
+	}
+	if err := cfg.db.DeleteChirp(ctx, chirpID); err != nil {
+		return err
+	}
+	for _, file := range files {
+		for _, key := range []string{file.StorageKey, file.ThumbnailKey} {
+			if err := cfg.media.Delete(ctx, key); err != nil {
+				log.Printf("deleting media %s: %s", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerChirpDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "You can only delete your own chirps")
+		return
+	}
+
+	if err := cfg.deleteChirp(r.Context(), chirp.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
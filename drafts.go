@@ -0,0 +1,165 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type draftParameters struct {
+	Body string `json:"body"`
+}
+
+func decodeDraft(r *http.Request) (draftParameters, error) {
+	decoder := json.NewDecoder(r.Body)
+	params := draftParameters{}
+	if err := decoder.Decode(&params); err != nil {
+		return draftParameters{}, errors.New("Invalid JSON:" + err.Error())
+	}
+	if len(params.Body) > 140 {
+		return draftParameters{}, errors.New("Chirp is too long")
+	}
+	return params, nil
+}
+
+func (cfg *apiConfig) handlerDraftCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	params, err := decodeDraft(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	draft, err := cfg.db.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID: userID,
+		Body:   params.Body,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, draft)
+}
+
+func (cfg *apiConfig) handlerDraftsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	drafts, err := cfg.db.GetDraftsByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if drafts == nil {
+		drafts = []database.Draft{}
+	}
+
+	respondWithJSON(w, http.StatusOK, drafts)
+}
+
+func (cfg *apiConfig) handlerDraftGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	draft, err := cfg.db.GetDraft(r.Context(), database.GetDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Draft not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, draft)
+}
+
+func (cfg *apiConfig) handlerDraftUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	params, err := decodeDraft(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	draft, err := cfg.db.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		Body:   params.Body,
+		ID:     draftID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Draft not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, draft)
+}
+
+func (cfg *apiConfig) handlerDraftDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	deleted, err := cfg.db.DeleteDraft(r.Context(), database.DeleteDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Draft not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
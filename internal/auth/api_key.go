@@ -17,4 +17,14 @@ func GetAPIKey(headers http.Header) (string, error) {
 	}
 
 	return strings.TrimPrefix(authHeader, "ApiKey "), nil
+}
+
+// APITokenPrefix starts every personal access token, so leaked tokens are
+// easy to recognise and secret scanners can look for them.
+const APITokenPrefix = "chirpy_pat_"
+
+// MakeAPIToken returns a new personal access token. Like refresh tokens it
+// is random, so it is stored with HashToken.
+func MakeAPIToken() string {
+	return APITokenPrefix + MakeRefreshToken()
 }
\ No newline at end of file
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidEmailToken = errors.New("invalid or expired token")
+	// ErrNoEmailTokenSecret is returned instead of signing with an empty
+	// key, which would let anyone forge tokens.
+	ErrNoEmailTokenSecret = errors.New("no email token secret")
+)
+
+type emailTokenClaims struct {
+	UserID    uuid.UUID `json:"sub"`
+	Email     string    `json:"email"`
+	Purpose   string    `json:"purpose"`
+	ExpiresAt int64     `json:"exp"`
+}
+
+// emailTokenKey derives the signing key from the server secret, so that an
+// email token can never be mistaken for an access token or the other way
+// round.
+func emailTokenKey(tokenSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte("chirpy email token"))
+	return mac.Sum(nil)
+}
+
+func signEmailToken(payload, tokenSecret string) string {
+	mac := hmac.New(sha256.New, emailTokenKey(tokenSecret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// MakeEmailToken signs a token that is sent to email and proves control of
+// that address when it comes back. purpose keeps a token issued for one
+// flow, such as verification, from being accepted by another.
+func MakeEmailToken(userID uuid.UUID, email, purpose, tokenSecret string, expiresIn time.Duration) (string, error) {
+	if tokenSecret == "" {
+		return "", ErrNoEmailTokenSecret
+	}
+	claims, err := json.Marshal(emailTokenClaims{
+		UserID:    userID,
+		Email:     email,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().UTC().Add(expiresIn).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + signEmailToken(payload, tokenSecret), nil
+}
+
+// ValidateEmailToken checks a token made by MakeEmailToken for purpose and
+// returns the user and email address it was issued for.
+func ValidateEmailToken(token, purpose, tokenSecret string) (uuid.UUID, string, error) {
+	if tokenSecret == "" {
+		return uuid.UUID{}, "", ErrNoEmailTokenSecret
+	}
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signEmailToken(payload, tokenSecret))) {
+		return uuid.UUID{}, "", ErrInvalidEmailToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return uuid.UUID{}, "", ErrInvalidEmailToken
+	}
+	claims := emailTokenClaims{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return uuid.UUID{}, "", ErrInvalidEmailToken
+	}
+	if claims.Purpose != purpose || time.Now().UTC().Unix() >= claims.ExpiresAt {
+		return uuid.UUID{}, "", ErrInvalidEmailToken
+	}
+
+	return claims.UserID, claims.Email, nil
+}
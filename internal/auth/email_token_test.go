@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEmailToken(t *testing.T) {
+	userID := uuid.New()
+	secret := "secret"
+
+	tests := []struct {
+		name        string
+		tokenSecret string
+		purpose     string
+		expiresIn   time.Duration
+		expectedErr error
+	}{
+		{
+			name:        "Valid token",
+			tokenSecret: secret,
+			purpose:     "verify",
+			expiresIn:   time.Hour,
+			expectedErr: nil,
+		},
+		{
+			name:        "Expired token",
+			tokenSecret: secret,
+			purpose:     "verify",
+			expiresIn:   -time.Second,
+			expectedErr: ErrInvalidEmailToken,
+		},
+		{
+			name:        "Signed with wrong secret",
+			tokenSecret: "wrong secret",
+			purpose:     "verify",
+			expiresIn:   time.Hour,
+			expectedErr: ErrInvalidEmailToken,
+		},
+		{
+			name:        "Issued for another purpose",
+			tokenSecret: secret,
+			purpose:     "reset",
+			expiresIn:   time.Hour,
+			expectedErr: ErrInvalidEmailToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := MakeEmailToken(userID, "walt@example.com", tt.purpose, tt.tokenSecret, tt.expiresIn)
+			if err != nil {
+				t.Fatalf("Error creating token: %v", err)
+			}
+			gotID, gotEmail, err := ValidateEmailToken(token, "verify", secret)
+			if err != tt.expectedErr {
+				t.Errorf("ValidateEmailToken() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if err == nil && (gotID != userID || gotEmail != "walt@example.com") {
+				t.Errorf("ValidateEmailToken() = %v, %s, expected %v, walt@example.com", gotID, gotEmail, userID)
+			}
+		})
+	}
+
+	keys := NewKeyring()
+	keys.AddLegacySecret(secret, time.Time{})
+	signingKey, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	keys.Rotate("test", signingKey, 0)
+	accessToken, err := MakeJWT(userID, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating JWT: %v", err)
+	}
+	if _, _, err := ValidateEmailToken(accessToken, "verify", secret); err != ErrInvalidEmailToken {
+		t.Errorf("ValidateEmailToken() accepted an access token")
+	}
+}
+
+func TestEmailTokenWithoutSecret(t *testing.T) {
+	if _, err := MakeEmailToken(uuid.New(), "walt@example.com", "verify", "", time.Hour); err != ErrNoEmailTokenSecret {
+		t.Errorf("MakeEmailToken() error = %v, expectedErr %v", err, ErrNoEmailTokenSecret)
+	}
+	// A token signed with an empty key must not be accepted by a server
+	// that forgot to configure one either.
+	payload := "eyJzdWIiOiIwMDAwMDAwMC0wMDAwLTAwMDAtMDAwMC0wMDAwMDAwMDAwMDAifQ"
+	token := payload + "." + signEmailToken(payload, "")
+	if _, _, err := ValidateEmailToken(token, "verify", ""); err != ErrNoEmailTokenSecret {
+		t.Errorf("ValidateEmailToken() error = %v, expectedErr %v", err, ErrNoEmailTokenSecret)
+	}
+}
@@ -2,6 +2,10 @@ package auth
 
 import "golang.org/x/crypto/bcrypt"
 
+// dummyHash is what passwords are checked against when the account
+// doesn't exist, so a failed login takes just as long either way.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("chirpy-dummy-password"), bcrypt.DefaultCost)
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
@@ -10,4 +14,11 @@ func HashPassword(password string) (string, error) {
 func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// CheckNoPassword does the work of CheckPasswordHash for a login to an
+// account that doesn't exist, and fails.
+func CheckNoPassword(password string) bool {
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+	return false
+}
@@ -1,8 +1,10 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,34 +12,116 @@ import (
 	"github.com/google/uuid"
 )
 
-const expireTime = time.Second * 3600
+const (
+	Issuer   = "chirpy"
+	Audience = "chirpy-api"
+)
 
-func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt: jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expireTime)),
-		Subject:   userID.String(),
-	})
+var (
+	ErrTokenExpired = errors.New("token is expired")
+	ErrTokenInvalid = errors.New("token is invalid")
+	// ErrInsufficientScope means the token is valid but its scopes don't
+	// cover what it was used for.
+	ErrInsufficientScope = errors.New("token doesn't grant access to this resource")
+)
+
+// TokenError is returned by ValidateJWT. Kind is ErrTokenExpired when the
+// token was valid but has expired, so the client should refresh it, and
+// ErrTokenInvalid for anything else.
+type TokenError struct {
+	Kind error
+	Err  error
+}
+
+func (e *TokenError) Error() string {
+	return e.Err.Error()
+}
 
-	return token.SignedString([]byte(tokenSecret))
+func (e *TokenError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
 }
 
-func ValidateJWT(tokenString string, tokenSecret string) (uuid.UUID, error) {
-	claims := jwt.RegisteredClaims{}
-	_, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (any, error) {
-		return []byte(tokenSecret), nil
+// AccessClaims are the claims of an access token. Scope and ClientID are
+// only set on tokens issued to third-party OAuth clients (RFC 9068), which
+// can only be used for what their scopes allow.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+func MakeJWT(userID uuid.UUID, keys *Keyring, expiresIn time.Duration) (string, error) {
+	return MakeClientJWT(userID, keys, expiresIn, "", nil)
+}
+
+// MakeClientJWT makes an access token for an OAuth client, limited to
+// scopes.
+func MakeClientJWT(userID uuid.UUID, keys *Keyring, expiresIn time.Duration, clientID string, scopes []string) (string, error) {
+	now := keys.now().UTC()
+	return keys.sign(AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Scope:    strings.Join(scopes, " "),
+		ClientID: clientID,
 	})
+}
+
+// ValidateJWT checks an access token like ParseAccessToken, but only
+// accepts first-party tokens that aren't limited to any scopes.
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	userID, scopes, err := ParseAccessToken(tokenString, keys)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
+	if scopes != nil {
+		return uuid.UUID{}, ErrInsufficientScope
+	}
+	return userID, nil
+}
 
-	userID, err := uuid.Parse(claims.Subject)
+// ParseAccessToken checks an access token's signature, issuer, audience
+// and validity window, allowing keys.Leeway of clock skew. scopes is nil
+// for first-party tokens, which can do anything the user can.
+func ParseAccessToken(tokenString string, keys *Keyring) (userID uuid.UUID, scopes []string, err error) {
+	claims := AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, keys.keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(Issuer),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(keys.Leeway),
+		jwt.WithTimeFunc(keys.now),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return uuid.UUID{}, nil, &TokenError{Kind: ErrTokenExpired, Err: err}
+	}
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, nil, &TokenError{Kind: ErrTokenInvalid, Err: err}
 	}
 
-	return userID, nil
+	// Tokens signed with the legacy secret predate the audience claim.
+	if kid, _ := token.Header["kid"].(string); kid != "" && !slices.Contains(claims.Audience, Audience) {
+		return uuid.UUID{}, nil, &TokenError{Kind: ErrTokenInvalid, Err: fmt.Errorf("token has invalid audience %v", claims.Audience)}
+	}
+
+	userID, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, nil, &TokenError{Kind: ErrTokenInvalid, Err: err}
+	}
+	if claims.ClientID != "" {
+		scopes = strings.Fields(claims.Scope)
+		if scopes == nil {
+			scopes = []string{}
+		}
+	}
+
+	return userID, scopes, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -51,4 +135,4 @@ func GetBearerToken(headers http.Header) (string, error) {
 	}
 
 	return strings.TrimPrefix(bearerToken, "Bearer "), nil
-}
\ No newline at end of file
+}
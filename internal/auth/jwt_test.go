@@ -1,56 +1,139 @@
 package auth
 
 import (
-	"fmt"
-	"strings"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 func TestJWT(t *testing.T) {
 	ogUserId := uuid.New()
-	secret := "secret"
+	now := time.Now()
+
+	newKeys := func(kid string) *Keyring {
+		key, err := GenerateSigningKey()
+		if err != nil {
+			t.Fatalf("Error generating key: %v", err)
+		}
+		keys := NewKeyring()
+		keys.now = func() time.Time { return now }
+		if err := keys.Rotate(kid, key, 0); err != nil {
+			t.Fatalf("Rotate() error = %v", err)
+		}
+		return keys
+	}
+	keys := newKeys("current")
+	keys.Leeway = 30 * time.Second
+	otherKeys := newKeys("current")
+
+	signed := func(keys *Keyring, claims jwt.RegisteredClaims) string {
+		token, err := keys.sign(claims)
+		if err != nil {
+			t.Fatalf("Error creating JWT: %v", err)
+		}
+		return token
+	}
+	claims := func(edit func(*jwt.RegisteredClaims)) jwt.RegisteredClaims {
+		c := jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			Subject:   ogUserId.String(),
+		}
+		edit(&c)
+		return c
+	}
+
+	validToken, err := MakeJWT(ogUserId, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating JWT: %v", err)
+	}
+	clientToken, err := MakeClientJWT(ogUserId, keys, time.Hour, uuid.NewString(), []string{"chirps:read"})
+	if err != nil {
+		t.Fatalf("Error creating JWT: %v", err)
+	}
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims(func(*jwt.RegisteredClaims) {})).SignedString(jwt.UnsafeAllowNoneSignatureType)
 
 	tests := []struct {
-		name 				 string
-		tokenSecret  string
-		expiresIn    time.Duration
-		expectedId   uuid.UUID
-		expectedErr  error
+		name        string
+		token       string
+		after       time.Duration
+		expectedId  uuid.UUID
+		expectedErr error
 	}{
 		{
-			name:         "Valid token",
-			tokenSecret:  secret,
-			expiresIn:    20 * time.Minute,
-			expectedId:   ogUserId,
-			expectedErr:  nil,
+			name:        "Valid token",
+			token:       validToken,
+			expectedId:  ogUserId,
+			expectedErr: nil,
+		},
+		{
+			name:        "Expired token",
+			token:       validToken,
+			after:       time.Hour + time.Minute,
+			expectedId:  uuid.UUID{},
+			expectedErr: ErrTokenExpired,
+		},
+		{
+			name:        "Expired within leeway",
+			token:       validToken,
+			after:       time.Hour + 10*time.Second,
+			expectedId:  ogUserId,
+			expectedErr: nil,
 		},
 		{
-			name:         "Expired token",
-			tokenSecret:  secret,
-			expiresIn:    0 * time.Second,
-			expectedId:   uuid.UUID{},
-			expectedErr:  fmt.Errorf("token is expired"),
+			name:        "Signed with wrong key",
+			token:       signed(otherKeys, claims(func(*jwt.RegisteredClaims) {})),
+			expectedId:  uuid.UUID{},
+			expectedErr: ErrTokenInvalid,
 		},
 		{
-			name:         "Signed with wrong secret",
-			tokenSecret:  "wrong secret",
-			expiresIn:    20 * time.Minute,
-			expectedId:   uuid.UUID{},
-			expectedErr:  fmt.Errorf("token signature is invalid"),
+			name:        "Unsigned token",
+			token:       unsigned,
+			expectedId:  uuid.UUID{},
+			expectedErr: ErrTokenInvalid,
+		},
+		{
+			name:        "Wrong issuer",
+			token:       signed(keys, claims(func(c *jwt.RegisteredClaims) { c.Issuer = "someone-else" })),
+			expectedId:  uuid.UUID{},
+			expectedErr: ErrTokenInvalid,
+		},
+		{
+			name:        "Wrong audience",
+			token:       signed(keys, claims(func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"other-api"} })),
+			expectedId:  uuid.UUID{},
+			expectedErr: ErrTokenInvalid,
+		},
+		{
+			name:        "No expiry",
+			token:       signed(keys, claims(func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil })),
+			expectedId:  uuid.UUID{},
+			expectedErr: ErrTokenInvalid,
+		},
+		{
+			name:        "Token limited to scopes",
+			token:       clientToken,
+			expectedId:  uuid.UUID{},
+			expectedErr: ErrInsufficientScope,
+		},
+		{
+			name:        "Not valid yet",
+			token:       signed(keys, claims(func(c *jwt.RegisteredClaims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Minute)) })),
+			expectedId:  uuid.UUID{},
+			expectedErr: ErrTokenInvalid,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tokenString, err := MakeJWT(ogUserId, tt.tokenSecret, tt.expiresIn)
-			if err != nil {
-				t.Errorf("Error creating JWT: %v", err)
-			}
-			userId, err := ValidateJWT(tokenString, secret)
+			keys.now = func() time.Time { return now.Add(tt.after) }
+			userId, err := ValidateJWT(tt.token, keys)
 
-			correctErr := (err == nil && tt.expectedErr == nil) || strings.Contains(err.Error(), tt.expectedErr.Error())
+			correctErr := (err == nil && tt.expectedErr == nil) || errors.Is(err, tt.expectedErr)
 			correctResult := tt.expectedId == userId
 
 			if !correctErr {
@@ -61,4 +144,4 @@ func TestJWT(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
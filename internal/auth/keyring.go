@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey  = errors.New("token signed with an unknown key")
+	ErrUnsupported = errors.New("unsupported key type")
+)
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    any
+	// notAfter is when a retired key stops verifying. Zero means never.
+	notAfter time.Time
+}
+
+// Keyring holds the key access tokens are signed with and every key they
+// are still accepted from. Each token names its key in the kid header, so
+// keys can be rotated without logging everyone out: a retired key keeps
+// verifying for an overlap period while the tokens it signed expire.
+type Keyring struct {
+	mu         sync.RWMutex
+	signingKID string
+	signingKey crypto.Signer
+	keys       map[string]verificationKey
+	now        func() time.Time
+
+	// Leeway is how far clocks may disagree when checking a token's exp,
+	// nbf and iat claims. Set it before the keyring is shared.
+	Leeway time.Duration
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string]verificationKey{}, now: time.Now}
+}
+
+func signingMethod(key any) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// AddVerificationKey accepts tokens signed by the private half of key
+// until notAfter, or for as long as the keyring lives if it is zero.
+func (k *Keyring) AddVerificationKey(kid string, key crypto.PublicKey, notAfter time.Time) error {
+	method, err := signingMethod(key)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[kid] = verificationKey{method: method, key: key, notAfter: notAfter}
+	return nil
+}
+
+// AddLegacySecret accepts HS256 tokens without a kid, as issued before
+// asymmetric signing, until notAfter. Nothing is signed with it.
+func (k *Keyring) AddLegacySecret(secret string, notAfter time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[""] = verificationKey{method: jwt.SigningMethodHS256, key: []byte(secret), notAfter: notAfter}
+}
+
+// Rotate makes key the signing key. The previous signing key keeps
+// verifying for overlap, which should be at least the access token
+// lifetime.
+func (k *Keyring) Rotate(kid string, key crypto.Signer, overlap time.Duration) error {
+	method, err := signingMethod(key)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.signingKID != "" && k.signingKID != kid {
+		previous := k.keys[k.signingKID]
+		previous.notAfter = k.now().Add(overlap)
+		k.keys[k.signingKID] = previous
+	}
+	k.signingKID = kid
+	k.signingKey = key
+	k.keys[kid] = verificationKey{method: method, key: key.Public()}
+	return nil
+}
+
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.signingKey == nil {
+		return "", errors.New("keyring has no signing key")
+	}
+
+	token := jwt.NewWithClaims(k.keys[k.signingKID].method, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.signingKey)
+}
+
+// keyfunc picks the verification key named by the token and refuses
+// tokens whose alg doesn't match it, so a public key can never be used as
+// an HMAC secret.
+func (k *Keyring) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok || (!key.notAfter.IsZero() && k.now().After(key.notAfter)) {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.key, nil
+}
+
+// JWK is a public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	KID string `json:"kid"`
+	KTY string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS returns the public keys tokens are currently accepted from, for
+// other services to verify them without sharing any secret.
+func (k *Keyring) JWKS() []JWK {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := []JWK{}
+	for kid, key := range k.keys {
+		if !key.notAfter.IsZero() && k.now().After(key.notAfter) {
+			continue
+		}
+		switch public := key.key.(type) {
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{KID: kid, KTY: "OKP", Alg: "EdDSA", Use: "sig", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(public)})
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{KID: kid, KTY: "RSA", Alg: "RS256", Use: "sig", N: base64.RawURLEncoding.EncodeToString(public.N.Bytes()), E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())})
+		}
+	}
+	return keys
+}
+
+// GenerateSigningKey returns a fresh Ed25519 key, for development setups
+// without a key directory.
+func GenerateSigningKey() (crypto.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// LoadKeyring reads every <kid>.pem file in dir. Private keys (PKCS #8)
+// can sign and public keys (PKIX) only verify; the key named signingKID
+// signs. To rotate, add a new private key, point signingKID at it and
+// delete the old file once the access tokens it signed have expired.
+// Keys can be made with `openssl genpkey -algorithm ed25519`.
+func LoadKeyring(dir, signingKID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keyring := NewKeyring()
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM data", path)
+		}
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			signer, ok := parsed.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("%s: %w", path, ErrUnsupported)
+			}
+			if kid == signingKID {
+				err = keyring.Rotate(kid, signer, 0)
+			} else {
+				err = keyring.AddVerificationKey(kid, signer.Public(), time.Time{})
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		case "PUBLIC KEY":
+			parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if err := keyring.AddVerificationKey(kid, parsed, time.Time{}); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unexpected PEM block %q", path, block.Type)
+		}
+	}
+
+	if keyring.signingKey == nil {
+		return nil, fmt.Errorf("no private key %s.pem in %s", signingKID, dir)
+	}
+	return keyring, nil
+}
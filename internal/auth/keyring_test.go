@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+	keys := NewKeyring()
+	keys.now = func() time.Time { return now }
+
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+
+	if err := keys.Rotate("old", oldKey, 0); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	oldToken, err := MakeJWT(userID, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	if err := keys.Rotate("new", newKey, 10*time.Minute); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	newToken, err := MakeJWT(userID, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: userID.String()})
+	forged.Header["kid"] = "old"
+	forgedToken, _ := forged.SignedString([]byte(oldKey.Public().(ed25519.PublicKey)))
+
+	tests := []struct {
+		name      string
+		token     string
+		after     time.Duration
+		expectErr bool
+	}{
+		{name: "Token from the new key", token: newToken, expectErr: false},
+		{name: "Token from the old key during overlap", token: oldToken, after: 5 * time.Minute, expectErr: false},
+		{name: "Token from the old key after overlap", token: oldToken, after: 11 * time.Minute, expectErr: true},
+		{name: "HS256 token keyed with a public key", token: forgedToken, expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys.now = func() time.Time { return now.Add(tt.after) }
+			got, err := ValidateJWT(tt.token, keys)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ValidateJWT() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if err == nil && got != userID {
+				t.Errorf("ValidateJWT() userId %v, expectedUserId %v", got, userID)
+			}
+		})
+	}
+
+	keys.now = func() time.Time { return now }
+	jwks := keys.JWKS()
+	if len(jwks) != 2 {
+		t.Fatalf("JWKS() returned %d keys, expected 2", len(jwks))
+	}
+	for _, key := range jwks {
+		if key.KID == "new" && (key.KTY != "RSA" || key.E != "AQAB") {
+			t.Errorf("JWKS() new key = %+v, expected an RSA key with e=AQAB", key)
+		}
+		if key.KID == "old" && (key.KTY != "OKP" || key.Crv != "Ed25519") {
+			t.Errorf("JWKS() old key = %+v, expected an Ed25519 key", key)
+		}
+	}
+}
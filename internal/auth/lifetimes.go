@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lifetimes is how long the access and refresh tokens issued to a client
+// last.
+type Lifetimes struct {
+	Access  time.Duration
+	Refresh time.Duration
+}
+
+// ParseClientLifetimes parses a list like "web=15m/24h,mobile=1h/2160h" of
+// access/refresh lifetimes per client. Client names are case-insensitive.
+func ParseClientLifetimes(s string) (map[string]Lifetimes, error) {
+	clients := map[string]Lifetimes{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		client, durations, ok := strings.Cut(entry, "=")
+		access, refresh, ok2 := strings.Cut(durations, "/")
+		if !ok || !ok2 || client == "" {
+			return nil, fmt.Errorf("invalid entry %q", entry)
+		}
+		accessLifetime, err := time.ParseDuration(access)
+		if err != nil || accessLifetime <= 0 {
+			return nil, fmt.Errorf("invalid entry %q", entry)
+		}
+		refreshLifetime, err := time.ParseDuration(refresh)
+		if err != nil || refreshLifetime <= 0 {
+			return nil, fmt.Errorf("invalid entry %q", entry)
+		}
+		clients[strings.ToLower(client)] = Lifetimes{Access: accessLifetime, Refresh: refreshLifetime}
+	}
+	return clients, nil
+}
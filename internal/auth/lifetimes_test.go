@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestParseClientLifetimes(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  map[string]Lifetimes
+		expectErr bool
+	}{
+		{name: "Empty", input: "", expected: map[string]Lifetimes{}},
+		{
+			name:  "Several clients",
+			input: "web=15m/24h, Mobile=1h/2160h",
+			expected: map[string]Lifetimes{
+				"web":    {Access: 15 * time.Minute, Refresh: 24 * time.Hour},
+				"mobile": {Access: time.Hour, Refresh: 2160 * time.Hour},
+			},
+		},
+		{name: "Trailing comma", input: "web=15m/24h,", expected: map[string]Lifetimes{"web": {Access: 15 * time.Minute, Refresh: 24 * time.Hour}}},
+		{name: "Missing name", input: "=15m/24h", expectErr: true},
+		{name: "Missing refresh lifetime", input: "web=15m", expectErr: true},
+		{name: "Missing lifetimes", input: "web", expectErr: true},
+		{name: "Invalid duration", input: "web=15x/24h", expectErr: true},
+		{name: "Zero lifetime", input: "web=0s/24h", expectErr: true},
+		{name: "Negative lifetime", input: "web=15m/-1h", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClientLifetimes(tt.input)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ParseClientLifetimes() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !maps.Equal(got, tt.expected) {
+				t.Errorf("ParseClientLifetimes() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import "time"
+
+const (
+	// FreeLoginAttempts is how many wrong passwords in a row an account
+	// gets before each further attempt has to wait.
+	FreeLoginAttempts = 3
+	// LockoutThreshold is the number of wrong passwords in a row that
+	// locks the account for LockoutDuration.
+	LockoutThreshold = 10
+	LockoutDuration  = 15 * time.Minute
+)
+
+// LoginBackoff is how long an account that has failed to log in failures
+// times in a row must wait before the next attempt. The wait doubles from
+// one second after the free attempts until the account is locked.
+func LoginBackoff(failures int) time.Duration {
+	if failures < FreeLoginAttempts {
+		return 0
+	}
+	if failures >= LockoutThreshold {
+		return LockoutDuration
+	}
+	return time.Second << (failures - FreeLoginAttempts)
+}
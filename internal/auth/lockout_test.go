@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		expected time.Duration
+	}{
+		{name: "No failures", failures: 0, expected: 0},
+		{name: "Within the free attempts", failures: FreeLoginAttempts - 1, expected: 0},
+		{name: "First delayed attempt", failures: FreeLoginAttempts, expected: time.Second},
+		{name: "Delay doubles", failures: FreeLoginAttempts + 2, expected: 4 * time.Second},
+		{name: "Last delay before lockout", failures: LockoutThreshold - 1, expected: 64 * time.Second},
+		{name: "Locked", failures: LockoutThreshold, expected: LockoutDuration},
+		{name: "Still locked", failures: LockoutThreshold + 20, expected: LockoutDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LoginBackoff(tt.failures); got != tt.expected {
+				t.Errorf("LoginBackoff(%d) = %v, expected %v", tt.failures, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckNoPassword(t *testing.T) {
+	hash, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	// Both have to run bcrypt at the same cost for their timing to match.
+	start := time.Now()
+	CheckPasswordHash("wrong", hash)
+	known := time.Since(start)
+	start = time.Now()
+	if CheckNoPassword("password") {
+		t.Errorf("CheckNoPassword() = true, expected false")
+	}
+	unknown := time.Since(start)
+
+	if unknown < known/4 {
+		t.Errorf("CheckNoPassword() took %v, much less than CheckPasswordHash() at %v", unknown, known)
+	}
+}
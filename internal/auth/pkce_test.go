@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mJ92K9u0kxbYU1m2N8eWWXmWm7zFrs"
+	challenge := PKCEChallenge(verifier)
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		expected  bool
+	}{
+		{name: "Matching verifier", verifier: verifier, challenge: challenge, expected: true},
+		{name: "Different verifier", verifier: strings.Repeat("a", 43), challenge: challenge, expected: false},
+		{name: "Verifier sent as the challenge", verifier: challenge, challenge: challenge, expected: false},
+		{name: "Verifier too short", verifier: "short", challenge: PKCEChallenge("short"), expected: false},
+		{name: "Verifier too long", verifier: strings.Repeat("a", 129), challenge: PKCEChallenge(strings.Repeat("a", 129)), expected: false},
+		{name: "Verifier with invalid characters", verifier: verifier + "/+", challenge: PKCEChallenge(verifier + "/+"), expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.expected {
+				t.Errorf("VerifyPKCE() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken returns the digest under which a random token is stored, so a
+// database leak doesn't hand out usable tokens. Tokens are long and random,
+// so unlike passwords they don't need a slow, salted hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
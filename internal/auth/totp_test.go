@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// The SHA-1 test vectors from RFC 6238, truncated to six digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		name     string
+		unix     int64
+		expected string
+	}{
+		{name: "T=59", unix: 59, expected: "287082"},
+		{name: "T=1111111109", unix: 1111111109, expected: "081804"},
+		{name: "T=1234567890", unix: 1234567890, expected: "005924"},
+		{name: "T=2000000000", unix: 2000000000, expected: "279037"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTPCode(secret, time.Unix(tt.unix, 0))
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+			if code != tt.expected {
+				t.Errorf("TOTPCode() = %s, expected %s", code, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := GenerateTOTPSecret()
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{
+		{name: "Current code", at: now, expected: true},
+		{name: "Previous period", at: now.Add(-30 * time.Second), expected: true},
+		{name: "Next period", at: now.Add(30 * time.Second), expected: true},
+		{name: "Too old", at: now.Add(-90 * time.Second), expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTPCode(secret, tt.at)
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+			step, ok := ValidateTOTP(secret, code, now)
+			if ok != tt.expected {
+				t.Errorf("ValidateTOTP() = %v, expected %v", ok, tt.expected)
+			}
+			if ok && step != tt.at.Unix()/30 {
+				t.Errorf("ValidateTOTP() step = %d, expected %d", step, tt.at.Unix()/30)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes := GenerateRecoveryCodes(10)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("GenerateRecoveryCodes() code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("GenerateRecoveryCodes() repeated %q", code)
+		}
+		seen[code] = true
+
+		typed := code[:5] + " " + code[6:]
+		if NormalizeRecoveryCode(typed) != code {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, expected %q", typed, NormalizeRecoveryCode(typed), code)
+		}
+	}
+}
@@ -7,29 +7,74 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const approveChirp = `-- name: ApproveChirp :one
+UPDATE chirps
+SET held = false, updated_at = now()
+WHERE id = $1 AND held = true
+RETURNING id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility
+`
+
+func (q *Queries) ApproveChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, approveChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Flagged,
+		&i.Held,
+		&i.PublishAt,
+		&i.Published,
+		&i.Visibility,
+	)
+	return i, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility)
 VALUES (
   gen_random_uuid(),
   now(),
   now(),
   $1,
-  $2
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body       string    `json:"body"`
+	UserID     uuid.UUID `json:"user_id"`
+	Flagged    bool      `json:"flagged"`
+	Held       bool      `json:"held"`
+	PublishAt  time.Time `json:"publish_at"`
+	Published  bool      `json:"published"`
+	Visibility string    `json:"visibility"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.Flagged,
+		arg.Held,
+		arg.PublishAt,
+		arg.Published,
+		arg.Visibility,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,12 +82,28 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Flagged,
+		&i.Held,
+		&i.PublishAt,
+		&i.Published,
+		&i.Visibility,
 	)
 	return i, err
 }
 
+const deleteChirp = `-- name: DeleteChirp :exec
+DELETE FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirp, id)
+	return err
+}
+
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility FROM chirps
+WHERE held = false AND published = true
 `
 
 func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
@@ -60,6 +121,11 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Flagged,
+			&i.Held,
+			&i.PublishAt,
+			&i.Published,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -75,7 +141,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility FROM chirps
 WHERE id = $1
 `
 
@@ -88,13 +154,18 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Flagged,
+		&i.Held,
+		&i.PublishAt,
+		&i.Published,
+		&i.Visibility,
 	)
 	return i, err
 }
 
 const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1
+SELECT id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility FROM chirps
+WHERE user_id = $1 AND held = false AND published = true
 `
 
 func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -112,6 +183,266 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Flagged,
+			&i.Held,
+			&i.PublishAt,
+			&i.Published,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility FROM chirps
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Flagged,
+			&i.Held,
+			&i.PublishAt,
+			&i.Published,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHeldChirps = `-- name: GetHeldChirps :many
+SELECT id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility FROM chirps
+WHERE held = true
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetHeldChirps(ctx context.Context) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getHeldChirps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Flagged,
+			&i.Held,
+			&i.PublishAt,
+			&i.Published,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOwnChirps = `-- name: GetOwnChirps :many
+SELECT id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility FROM chirps
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetOwnChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getOwnChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Flagged,
+			&i.Held,
+			&i.PublishAt,
+			&i.Published,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getScheduledChirpsByAuthor = `-- name: GetScheduledChirpsByAuthor :many
+SELECT id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility FROM chirps
+WHERE user_id = $1 AND published = false
+ORDER BY publish_at ASC
+`
+
+func (q *Queries) GetScheduledChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledChirpsByAuthor, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Flagged,
+			&i.Held,
+			&i.PublishAt,
+			&i.Published,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimelineChirps = `-- name: GetTimelineChirps :many
+SELECT id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility FROM chirps
+WHERE user_id = ANY($1::uuid[])
+  AND held = false AND published = true
+  AND (publish_at, id) < ($2::timestamp, $3::uuid)
+ORDER BY publish_at DESC, id DESC
+LIMIT $4::int
+`
+
+type GetTimelineChirpsParams struct {
+	UserIds         []uuid.UUID `json:"user_ids"`
+	BeforePublishAt time.Time   `json:"before_publish_at"`
+	BeforeID        uuid.UUID   `json:"before_id"`
+	PageSize        int32       `json:"page_size"`
+}
+
+func (q *Queries) GetTimelineChirps(ctx context.Context, arg GetTimelineChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimelineChirps,
+		pq.Array(arg.UserIds),
+		arg.BeforePublishAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Flagged,
+			&i.Held,
+			&i.PublishAt,
+			&i.Published,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishDueChirps = `-- name: PublishDueChirps :many
+UPDATE chirps
+SET published = true, updated_at = now()
+WHERE published = false AND publish_at <= $1::timestamp
+RETURNING id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility
+`
+
+func (q *Queries) PublishDueChirps(ctx context.Context, now time.Time) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, publishDueChirps, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Flagged,
+			&i.Held,
+			&i.PublishAt,
+			&i.Published,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -11,28 +11,266 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID    `json:"id"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Name       string       `json:"name"`
+	TokenHash  string       `json:"token_hash"`
+	Scopes     string       `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+}
+
+type BannedWord struct {
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Bookmark struct {
+	ID           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UserID       uuid.UUID     `json:"user_id"`
+	ChirpID      uuid.UUID     `json:"chirp_id"`
+	CollectionID uuid.NullUUID `json:"collection_id"`
+}
+
+type BookmarkCollection struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+}
+
 type Chirp struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Body       string    `json:"body"`
+	UserID     uuid.UUID `json:"user_id"`
+	Flagged    bool      `json:"flagged"`
+	Held       bool      `json:"held"`
+	PublishAt  time.Time `json:"publish_at"`
+	Published  bool      `json:"published"`
+	Visibility string    `json:"visibility"`
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+type Draft struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
 	Body      string    `json:"body"`
+}
+
+type ExternalIdentity struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
 	UserID    uuid.UUID `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
 }
 
-type RefreshToken struct {
-	Token     string       `json:"token"`
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Approved   bool      `json:"approved"`
+}
+
+type List struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	Private   bool      `json:"private"`
+}
+
+type ListMember struct {
+	ListID    uuid.UUID `json:"list_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LoginEmailFailure struct {
+	Email        string       `json:"email"`
+	LastFailedAt time.Time    `json:"last_failed_at"`
+	Failures     int32        `json:"failures"`
+	LockedUntil  sql.NullTime `json:"locked_until"`
+}
+
+type LoginIpFailure struct {
+	IpAddress       string       `json:"ip_address"`
+	WindowStartedAt time.Time    `json:"window_started_at"`
+	Failures        int32        `json:"failures"`
+	LockedUntil     sql.NullTime `json:"locked_until"`
+}
+
+type MagicLink struct {
+	TokenHash  string       `json:"token_hash"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UserID     uuid.UUID    `json:"user_id"`
+	DeviceHash string       `json:"device_hash"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	UsedAt     sql.NullTime `json:"used_at"`
+}
+
+type MediaFile struct {
+	ID           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	UserID       uuid.UUID     `json:"user_id"`
+	ChirpID      uuid.NullUUID `json:"chirp_id"`
+	Position     int32         `json:"position"`
+	ContentType  string        `json:"content_type"`
+	Size         int32         `json:"size"`
+	Width        int32         `json:"width"`
+	Height       int32         `json:"height"`
+	StorageKey   string        `json:"storage_key"`
+	ThumbnailKey string        `json:"thumbnail_key"`
+}
+
+type MfaChallenge struct {
+	TokenHash string    `json:"token_hash"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Attempts  int32     `json:"attempts"`
+}
+
+type OauthClient struct {
+	ID           uuid.UUID      `json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	OwnerID      uuid.UUID      `json:"owner_id"`
+	Name         string         `json:"name"`
+	SecretHash   sql.NullString `json:"secret_hash"`
+	RedirectUris string         `json:"redirect_uris"`
+}
+
+type OauthCode struct {
+	CodeHash      string    `json:"code_hash"`
+	CreatedAt     time.Time `json:"created_at"`
+	ClientID      uuid.UUID `json:"client_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	RedirectUri   string    `json:"redirect_uri"`
+	Scopes        string    `json:"scopes"`
+	CodeChallenge string    `json:"code_challenge"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type OidcState struct {
+	StateHash    string    `json:"state_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+	Provider     string    `json:"provider"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type Passkey struct {
+	ID           uuid.UUID    `json:"id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UserID       uuid.UUID    `json:"user_id"`
+	Name         string       `json:"name"`
+	CredentialID []byte       `json:"credential_id"`
+	PublicKey    []byte       `json:"public_key"`
+	SignCount    int64        `json:"sign_count"`
+	LastUsedAt   sql.NullTime `json:"last_used_at"`
+}
+
+type PasswordResetToken struct {
+	TokenHash string       `json:"token_hash"`
 	CreatedAt time.Time    `json:"created_at"`
-	UpdatedAt time.Time    `json:"updated_at"`
 	UserID    uuid.UUID    `json:"user_id"`
 	ExpiresAt time.Time    `json:"expires_at"`
-	RevokedAt sql.NullTime `json:"revoked_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+}
+
+type PinnedChirp struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Poll struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PollOption struct {
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	Position int32     `json:"position"`
+	Label    string    `json:"label"`
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Position  int32     `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	CreatedAt time.Time    `json:"created_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+}
+
+type RefreshToken struct {
+	Token         string        `json:"token"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	UserID        uuid.UUID     `json:"user_id"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	RevokedAt     sql.NullTime  `json:"revoked_at"`
+	ID            uuid.UUID     `json:"id"`
+	UserAgent     string        `json:"user_agent"`
+	IpAddress     string        `json:"ip_address"`
+	LastUsedAt    sql.NullTime  `json:"last_used_at"`
+	Client        string        `json:"client"`
+	OauthClientID uuid.NullUUID `json:"oauth_client_id"`
+	Scopes        string        `json:"scopes"`
+}
+
+type RoleChange struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UserID    uuid.NullUUID `json:"user_id"`
+	ChangedBy uuid.NullUUID `json:"changed_by"`
+	OldRole   string        `json:"old_role"`
+	NewRole   string        `json:"new_role"`
 }
 
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ID                  uuid.UUID      `json:"id"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	Email               string         `json:"email"`
+	HashedPassword      string         `json:"hashed_password"`
+	IsChirpyRed         bool           `json:"is_chirpy_red"`
+	Protected           bool           `json:"protected"`
+	DeletionRequestedAt sql.NullTime   `json:"deletion_requested_at"`
+	EmailVerifiedAt     sql.NullTime   `json:"email_verified_at"`
+	TotpSecret          sql.NullString `json:"totp_secret"`
+	TotpEnabled         bool           `json:"totp_enabled"`
+	TotpLastStep        int64          `json:"totp_last_step"`
+	Role                string         `json:"role"`
+}
+
+type WebauthnChallenge struct {
+	ChallengeHash string        `json:"challenge_hash"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UserID        uuid.NullUUID `json:"user_id"`
+	Ceremony      string        `json:"ceremony"`
+	ExpiresAt     time.Time     `json:"expires_at"`
 }
@@ -13,17 +13,77 @@ import (
 	"github.com/google/uuid"
 )
 
+const createOAuthRefreshToken = `-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, user_agent, ip_address, last_used_at, oauth_client_id, scopes)
+VALUES (
+  $1,
+  now(),
+  now(),
+  $2,
+  $3,
+  $4,
+  $5,
+  now(),
+  $6,
+  $7
+)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, id, user_agent, ip_address, last_used_at, client, oauth_client_id, scopes
+`
+
+type CreateOAuthRefreshTokenParams struct {
+	Token         string        `json:"token"`
+	UserID        uuid.UUID     `json:"user_id"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	UserAgent     string        `json:"user_agent"`
+	IpAddress     string        `json:"ip_address"`
+	OauthClientID uuid.NullUUID `json:"oauth_client_id"`
+	Scopes        string        `json:"scopes"`
+}
+
+func (q *Queries) CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.OauthClientID,
+		arg.Scopes,
+	)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.ID,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.Client,
+		&i.OauthClientID,
+		&i.Scopes,
+	)
+	return i, err
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, user_agent, ip_address, last_used_at, client)
 VALUES (
   $1,
   now(),
   now(),
   $2,
   $3,
-  $4
+  $4,
+  $5,
+  $6,
+  now(),
+  $7
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, id, user_agent, ip_address, last_used_at, client, oauth_client_id, scopes
 `
 
 type CreateRefreshTokenParams struct {
@@ -31,6 +91,9 @@ type CreateRefreshTokenParams struct {
 	UserID    uuid.UUID    `json:"user_id"`
 	ExpiresAt time.Time    `json:"expires_at"`
 	RevokedAt sql.NullTime `json:"revoked_at"`
+	UserAgent string       `json:"user_agent"`
+	IpAddress string       `json:"ip_address"`
+	Client    string       `json:"client"`
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
@@ -39,6 +102,9 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		arg.UserID,
 		arg.ExpiresAt,
 		arg.RevokedAt,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.Client,
 	)
 	var i RefreshToken
 	err := row.Scan(
@@ -48,20 +114,182 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ID,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.Client,
+		&i.OauthClientID,
+		&i.Scopes,
+	)
+	return i, err
+}
+
+const getActiveSessions = `-- name: GetActiveSessions :many
+SELECT id, created_at, user_agent, ip_address, client, last_used_at, expires_at FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2::timestamp
+ORDER BY last_used_at DESC
+`
+
+type GetActiveSessionsParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Now    time.Time `json:"now"`
+}
+
+type GetActiveSessionsRow struct {
+	ID         uuid.UUID    `json:"id"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UserAgent  string       `json:"user_agent"`
+	IpAddress  string       `json:"ip_address"`
+	Client     string       `json:"client"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+}
+
+func (q *Queries) GetActiveSessions(ctx context.Context, arg GetActiveSessionsParams) ([]GetActiveSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveSessions, arg.UserID, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveSessionsRow
+	for rows.Next() {
+		var i GetActiveSessionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.Client,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOAuthGrantsByUser = `-- name: GetOAuthGrantsByUser :many
+SELECT id, created_at, oauth_client_id, scopes, expires_at FROM refresh_tokens
+WHERE user_id = $1 AND oauth_client_id IS NOT NULL AND revoked_at IS NULL AND expires_at > $2::timestamp
+ORDER BY created_at
+`
+
+type GetOAuthGrantsByUserParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Now    time.Time `json:"now"`
+}
+
+type GetOAuthGrantsByUserRow struct {
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	OauthClientID uuid.NullUUID `json:"oauth_client_id"`
+	Scopes        string        `json:"scopes"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+}
+
+func (q *Queries) GetOAuthGrantsByUser(ctx context.Context, arg GetOAuthGrantsByUserParams) ([]GetOAuthGrantsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOAuthGrantsByUser, arg.UserID, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOAuthGrantsByUserRow
+	for rows.Next() {
+		var i GetOAuthGrantsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.OauthClientID,
+			&i.Scopes,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOAuthRefreshToken = `-- name: GetOAuthRefreshToken :one
+SELECT user_id, scopes FROM refresh_tokens
+WHERE token = $1 AND oauth_client_id = $2 AND revoked_at IS NULL AND expires_at > $3::timestamp
+`
+
+type GetOAuthRefreshTokenParams struct {
+	Token         string        `json:"token"`
+	OauthClientID uuid.NullUUID `json:"oauth_client_id"`
+	Now           time.Time     `json:"now"`
+}
+
+type GetOAuthRefreshTokenRow struct {
+	UserID uuid.UUID `json:"user_id"`
+	Scopes string    `json:"scopes"`
+}
+
+func (q *Queries) GetOAuthRefreshToken(ctx context.Context, arg GetOAuthRefreshTokenParams) (GetOAuthRefreshTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, getOAuthRefreshToken, arg.Token, arg.OauthClientID, arg.Now)
+	var i GetOAuthRefreshTokenRow
+	err := row.Scan(
+		&i.UserID,
+		&i.Scopes,
 	)
 	return i, err
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT user_id FROM refresh_tokens
-WHERE token = $1 AND revoked_at IS NULL
+SELECT user_id, client FROM refresh_tokens
+WHERE token = $1 AND revoked_at IS NULL AND expires_at > $2::timestamp AND oauth_client_id IS NULL
 `
 
-func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (uuid.UUID, error) {
-	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
-	var user_id uuid.UUID
-	err := row.Scan(&user_id)
-	return user_id, err
+type GetUserFromRefreshTokenParams struct {
+	Token string    `json:"token"`
+	Now   time.Time `json:"now"`
+}
+
+type GetUserFromRefreshTokenRow struct {
+	UserID uuid.UUID `json:"user_id"`
+	Client string    `json:"client"`
+}
+
+func (q *Queries) GetUserFromRefreshToken(ctx context.Context, arg GetUserFromRefreshTokenParams) (GetUserFromRefreshTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, arg.Token, arg.Now)
+	var i GetUserFromRefreshTokenRow
+	err := row.Scan(
+		&i.UserID,
+		&i.Client,
+	)
+	return i, err
+}
+
+const revokeOAuthRefreshToken = `-- name: RevokeOAuthRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = now(), updated_at = now()
+WHERE token = $1 AND oauth_client_id = $2
+`
+
+type RevokeOAuthRefreshTokenParams struct {
+	Token         string        `json:"token"`
+	OauthClientID uuid.NullUUID `json:"oauth_client_id"`
+}
+
+func (q *Queries) RevokeOAuthRefreshToken(ctx context.Context, arg RevokeOAuthRefreshTokenParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOAuthRefreshToken, arg.Token, arg.OauthClientID)
+	return err
 }
 
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
@@ -74,3 +302,49 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
 	return err
 }
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = now(), updated_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = now(), updated_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
+
+const touchRefreshToken = `-- name: TouchRefreshToken :exec
+UPDATE refresh_tokens
+SET last_used_at = now(), ip_address = $2
+WHERE token = $1
+`
+
+type TouchRefreshTokenParams struct {
+	Token     string `json:"token"`
+	IpAddress string `json:"ip_address"`
+}
+
+func (q *Queries) TouchRefreshToken(ctx context.Context, arg TouchRefreshTokenParams) error {
+	_, err := q.db.ExecContext(ctx, touchRefreshToken, arg.Token, arg.IpAddress)
+	return err
+}
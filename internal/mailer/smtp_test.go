@@ -0,0 +1,113 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStandIn accepts a single session on a local port and records the
+// envelope and message it receives.
+type smtpStandIn struct {
+	listener net.Listener
+	from     string
+	to       string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	s := &smtpStandIn{listener: listener, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.to = strings.Trim(line[len("RCPT TO:"):], "<>")
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	server := newSMTPStandIn(t)
+	defer server.listener.Close()
+
+	m := SMTPMailer{Addr: server.listener.Addr().String(), From: "chirpy@example.com"}
+	err := m.Send(context.Background(), Message{
+		To:      "walt@example.com",
+		Subject: "Verify your email",
+		Body:    "Open this link:\nhttp://localhost/verify",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	<-server.done
+
+	if server.from != "chirpy@example.com" || server.to != "walt@example.com" {
+		t.Errorf("envelope = %s -> %s, expected chirpy@example.com -> walt@example.com", server.from, server.to)
+	}
+	for _, expected := range []string{"Subject: Verify your email\r\n", "To: walt@example.com\r\n", "\r\nhttp://localhost/verify\r\n"} {
+		if !strings.Contains(server.data, expected) {
+			t.Errorf("message is missing %q:\n%s", expected, server.data)
+		}
+	}
+}
+
+func TestMessageRejectsHeaderInjection(t *testing.T) {
+	msg := Message{To: "walt@example.com\r\nBcc: everyone@example.com", Subject: "Hi"}
+	if _, err := msg.format("chirpy@example.com", time.Time{}); err != ErrInvalidHeader {
+		t.Errorf("format() error = %v, expected %v", err, ErrInvalidHeader)
+	}
+}
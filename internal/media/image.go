@@ -0,0 +1,232 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"slices"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrImageTooLarge   = errors.New("image is too large")
+)
+
+var imageTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// ThumbnailSize is the longest side, in pixels, of generated thumbnails.
+const ThumbnailSize = 320
+
+// Uploads are checked against these limits before their pixels are
+// decoded, since a small file can declare huge dimensions or, for a GIF,
+// thousands of frames.
+const (
+	MaxImageSide   = 8192
+	MaxImagePixels = 32 << 20
+	MaxGIFFrames   = 300
+	// MaxGIFPixels caps the pixels of all of a GIF's frames together.
+	MaxGIFPixels = 128 << 20
+)
+
+// Image is an upload after processing. Data and Thumbnail are re-encoded
+// from the decoded pixels, so EXIF and any other metadata in the original
+// file is dropped.
+type Image struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+	Thumbnail   []byte
+}
+
+// SniffContentType detects the type of an upload from its content instead
+// of trusting the client supplied header.
+func SniffContentType(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+func ProcessImage(data []byte) (Image, error) {
+	contentType := SniffContentType(data)
+	if !slices.Contains(imageTypes, contentType) {
+		return Image{}, ErrUnsupportedType
+	}
+	if err := checkImageSize(data, contentType); err != nil {
+		return Image{}, err
+	}
+
+	var img image.Image
+	var encoded bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		decoded, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		img = decoded
+		if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 90}); err != nil {
+			return Image{}, err
+		}
+	case "image/png":
+		decoded, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		img = decoded
+		if err := png.Encode(&encoded, img); err != nil {
+			return Image{}, err
+		}
+	case "image/gif":
+		decoded, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		img = decoded.Image[0]
+		if err := gif.EncodeAll(&encoded, &gif.GIF{
+			Image:     decoded.Image,
+			Delay:     decoded.Delay,
+			LoopCount: decoded.LoopCount,
+			Disposal:  decoded.Disposal,
+			Config:    decoded.Config,
+		}); err != nil {
+			return Image{}, err
+		}
+	default:
+		return Image{}, ErrUnsupportedType
+	}
+
+	thumbnail, err := makeThumbnail(img, contentType)
+	if err != nil {
+		return Image{}, err
+	}
+
+	bounds := img.Bounds()
+	return Image{
+		ContentType: contentType,
+		Data:        encoded.Bytes(),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Thumbnail:   thumbnail,
+	}, nil
+}
+
+// checkImageSize rejects images whose decoded pixels would exceed the
+// limits, reading only their headers.
+func checkImageSize(data []byte, contentType string) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if config.Width > MaxImageSide || config.Height > MaxImageSide ||
+		config.Width*config.Height > MaxImagePixels {
+		return ErrImageTooLarge
+	}
+	if contentType != "image/gif" {
+		return nil
+	}
+
+	frames, err := countGIFFrames(data)
+	if err != nil {
+		return err
+	}
+	if frames > MaxGIFFrames || frames*config.Width*config.Height > MaxGIFPixels {
+		return ErrImageTooLarge
+	}
+	return nil
+}
+
+// countGIFFrames walks the blocks of a GIF without decompressing any of
+// them, counting image descriptors.
+func countGIFFrames(data []byte) (int, error) {
+	errMalformed := errors.New("gif: malformed file")
+	// Header and logical screen descriptor.
+	if len(data) < 13 {
+		return 0, errMalformed
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos += 1 + size
+			if size == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	frames := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension: label, then sub-blocks.
+			pos += 2
+			if !skipSubBlocks() {
+				return 0, errMalformed
+			}
+		case 0x2C: // Image descriptor, local color table, LZW code size.
+			if pos+10 > len(data) {
+				return 0, errMalformed
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++
+			if !skipSubBlocks() {
+				return 0, errMalformed
+			}
+			frames++
+			if frames > MaxGIFFrames {
+				return frames, nil
+			}
+		case 0x3B: // Trailer.
+			return frames, nil
+		default:
+			return 0, errMalformed
+		}
+	}
+	return 0, errMalformed
+}
+
+// ThumbnailContentType is the type makeThumbnail encodes to for an image of
+// the given type. PNGs keep transparency, everything else becomes a JPEG.
+func ThumbnailContentType(contentType string) string {
+	if contentType == "image/png" {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+func makeThumbnail(img image.Image, contentType string) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > ThumbnailSize || height > ThumbnailSize {
+		if width >= height {
+			width, height = ThumbnailSize, max(1, height*ThumbnailSize/width)
+		} else {
+			width, height = max(1, width*ThumbnailSize/height), ThumbnailSize
+		}
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(thumb, thumb.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	var err error
+	if ThumbnailContentType(contentType) == "image/png" {
+		err = png.Encode(&buf, thumb)
+	} else {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,117 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withExif splices a minimal APP1 Exif segment right after the JPEG SOI
+// marker, the way cameras do.
+func withExif(jpg []byte) []byte {
+	payload := []byte("Exif\x00\x00GPS-SECRET-LOCATION")
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func TestProcessImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for x := range 800 {
+		for y := range 400 {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Error encoding JPEG: %v", err)
+	}
+	upload := withExif(buf.Bytes())
+
+	processed, err := ProcessImage(upload)
+	if err != nil {
+		t.Fatalf("ProcessImage() error = %v", err)
+	}
+	if processed.ContentType != "image/jpeg" {
+		t.Errorf("ProcessImage() content type = %s, expected image/jpeg", processed.ContentType)
+	}
+	if bytes.Contains(processed.Data, []byte("GPS-SECRET-LOCATION")) {
+		t.Errorf("ProcessImage() kept EXIF data")
+	}
+	if processed.Width != 800 || processed.Height != 400 {
+		t.Errorf("ProcessImage() size = %dx%d, expected 800x400", processed.Width, processed.Height)
+	}
+
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(processed.Thumbnail))
+	if err != nil {
+		t.Fatalf("Error decoding thumbnail: %v", err)
+	}
+	if thumb.Width != ThumbnailSize || thumb.Height != ThumbnailSize/2 {
+		t.Errorf("Thumbnail size = %dx%d, expected %dx%d", thumb.Width, thumb.Height, ThumbnailSize, ThumbnailSize/2)
+	}
+
+	if _, err := ProcessImage([]byte("%PDF-1.4 not an image")); err != ErrUnsupportedType {
+		t.Errorf("ProcessImage() error = %v, expected %v", err, ErrUnsupportedType)
+	}
+}
+
+// withPNGSize rewrites the dimensions in a PNG's header, so it claims to be
+// far larger than the pixels it holds.
+func withPNGSize(data []byte, width, height uint32) []byte {
+	out := append([]byte{}, data...)
+	// Signature, chunk length and type, then the IHDR fields.
+	binary.BigEndian.PutUint32(out[16:], width)
+	binary.BigEndian.PutUint32(out[20:], height)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func animatedGIF(t *testing.T, frames int) []byte {
+	anim := &gif.GIF{}
+	for range frames {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("Error encoding GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessImageLimits(t *testing.T) {
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("Error encoding PNG: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		upload      []byte
+		expectedErr error
+	}{
+		{name: "Small PNG", upload: small.Bytes(), expectedErr: nil},
+		{name: "PNG claiming a huge width", upload: withPNGSize(small.Bytes(), MaxImageSide+1, 4), expectedErr: ErrImageTooLarge},
+		{name: "PNG claiming too many pixels", upload: withPNGSize(small.Bytes(), 8000, 8000), expectedErr: ErrImageTooLarge},
+		{name: "Short animated GIF", upload: animatedGIF(t, 10), expectedErr: nil},
+		{name: "GIF with too many frames", upload: animatedGIF(t, MaxGIFFrames+1), expectedErr: ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ProcessImage(tt.upload); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("ProcessImage() error = %v, expected %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
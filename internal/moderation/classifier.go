@@ -0,0 +1,95 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// HTTPClassifier delegates the decision to an external service. It POSTs
+// {"body": "..."} to URL and expects {"action": "...", "body": "...",
+// "reason": "..."} back, where action is one of the Action values.
+type HTTPClassifier struct {
+	URL    string
+	Client *http.Client
+	// OnError is the action taken when the service can't be reached or
+	// answers with something unusable.
+	OnError Action
+}
+
+func NewHTTPClassifier(url string) *HTTPClassifier {
+	return &HTTPClassifier{
+		URL:     url,
+		Client:  &http.Client{Timeout: 2 * time.Second},
+		OnError: ActionHold,
+	}
+}
+
+func (c *HTTPClassifier) Name() string {
+	return "classifier"
+}
+
+func (c *HTTPClassifier) Moderate(ctx context.Context, body string) (Verdict, error) {
+	verdict, err := c.classify(ctx, body)
+	if err != nil {
+		return Verdict{
+			Action: c.OnError,
+			Body:   body,
+			Reason: err.Error(),
+		}, nil
+	}
+	return verdict, nil
+}
+
+func (c *HTTPClassifier) classify(ctx context.Context, body string) (Verdict, error) {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(payload))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("classifier responded with %s", res.Status)
+	}
+
+	var response struct {
+		Action string `json:"action"`
+		Body   string `json:"body"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return Verdict{}, fmt.Errorf("invalid classifier response: %w", err)
+	}
+
+	action, err := ParseAction(response.Action)
+	if err != nil {
+		return Verdict{}, err
+	}
+	// Masking only hides characters, so a body of another length means the
+	// classifier rewrote the chirp, which could also take it over the
+	// length limit.
+	if action == ActionMask && utf8.RuneCountInString(response.Body) != utf8.RuneCountInString(body) {
+		return Verdict{}, fmt.Errorf("classifier asked to mask with a body of a different length")
+	}
+
+	return Verdict{
+		Action: action,
+		Body:   response.Body,
+		Reason: response.Reason,
+	}, nil
+}
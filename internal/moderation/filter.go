@@ -0,0 +1,111 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+const mask = "****"
+
+type Result struct {
+	// Masked is the input with every banned word replaced by asterisks.
+	Masked  string
+	Matches []string
+}
+
+func (r Result) Clean() bool {
+	return len(r.Matches) == 0
+}
+
+// Filter matches text against a word list that can be swapped at runtime.
+// As a pipeline stage it answers with its configured action whenever a
+// banned word is found.
+type Filter struct {
+	source Source
+	action Action
+
+	mu    sync.RWMutex
+	words map[string]bool
+}
+
+func NewFilter(source Source, action Action) *Filter {
+	return &Filter{
+		source: source,
+		action: action,
+		words:  map[string]bool{},
+	}
+}
+
+func (f *Filter) Name() string {
+	return "words"
+}
+
+func (f *Filter) Moderate(ctx context.Context, body string) (Verdict, error) {
+	result := f.Check(body)
+	if result.Clean() {
+		return Verdict{Action: ActionAllow}, nil
+	}
+
+	return Verdict{
+		Action: f.action,
+		Body:   result.Masked,
+		Reason: "banned words: " + strings.Join(result.Matches, ", "),
+	}, nil
+}
+
+// Reload replaces the word list with the current contents of the source
+// and returns the number of words loaded.
+func (f *Filter) Reload(ctx context.Context) (int, error) {
+	list, err := f.source.Load(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	words := make(map[string]bool, len(list))
+	for _, word := range list {
+		if normalized := Normalize(word); normalized != "" {
+			words[normalized] = true
+		}
+	}
+
+	f.mu.Lock()
+	f.words = words
+	f.mu.Unlock()
+
+	return len(words), nil
+}
+
+func (f *Filter) Check(s string) Result {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var b strings.Builder
+	var matches []string
+	last := 0
+	for _, w := range words(s) {
+		word := s[w.start:w.end]
+		if !f.matches(word) {
+			continue
+		}
+		matches = append(matches, word)
+		b.WriteString(s[last:w.start])
+		b.WriteString(mask)
+		last = w.end
+	}
+	b.WriteString(s[last:])
+
+	return Result{
+		Masked:  b.String(),
+		Matches: matches,
+	}
+}
+
+func (f *Filter) matches(word string) bool {
+	for _, form := range forms(word) {
+		if f.words[form] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFilterCheck(t *testing.T) {
+	source := SourceFunc(func(ctx context.Context) ([]string, error) {
+		return []string{"kerfuffle", "sharbert", "fornax"}, nil
+	})
+	filter := NewFilter(source, ActionMask)
+	if _, err := filter.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		input          string
+		expectedMasked string
+		expectedCount  int
+	}{
+		{
+			name:           "Clean chirp",
+			input:          "I had something interesting for breakfast",
+			expectedMasked: "I had something interesting for breakfast",
+			expectedCount:  0,
+		},
+		{
+			name:           "Mixed case",
+			input:          "This is a Kerfuffle opinion",
+			expectedMasked: "This is a **** opinion",
+			expectedCount:  1,
+		},
+		{
+			name:           "Trailing punctuation",
+			input:          "What a kerfuffle! Sharbert, again.",
+			expectedMasked: "What a ****! ****, again.",
+			expectedCount:  2,
+		},
+		{
+			name:           "Diacritics",
+			input:          "fòrnáx is here",
+			expectedMasked: "**** is here",
+			expectedCount:  1,
+		},
+		{
+			name:           "Leetspeak",
+			input:          "k3rfuff1e and $h@rb3rt",
+			expectedMasked: "**** and ****",
+			expectedCount:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := filter.Check(tt.input)
+			if result.Masked != tt.expectedMasked {
+				t.Errorf("Check() masked = %q, expected %q", result.Masked, tt.expectedMasked)
+			}
+			if len(result.Matches) != tt.expectedCount {
+				t.Errorf("Check() matches = %v, expected %d", result.Matches, tt.expectedCount)
+			}
+		})
+	}
+}
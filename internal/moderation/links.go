@@ -0,0 +1,112 @@
+package moderation
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// linkPattern matches links with a scheme or www. prefix, and bare
+// host.tld links with an optional port and path, since most clients turn
+// those into links too.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:(?:https?://|www\.)[^\s<>"]+|(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,63}\b(?::\d+)?(?:/[^\s<>"]*)?)`)
+
+// links returns every URL-looking substring of s.
+func links(s string) []string {
+	return linkPattern.FindAllString(s, -1)
+}
+
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+}
+
+// LinkBlocklist answers with its action when a chirp links to a blocked
+// domain or any of its subdomains.
+type LinkBlocklist struct {
+	source Source
+	action Action
+
+	mu      sync.RWMutex
+	domains map[string]bool
+}
+
+func NewLinkBlocklist(source Source, action Action) *LinkBlocklist {
+	return &LinkBlocklist{
+		source:  source,
+		action:  action,
+		domains: map[string]bool{},
+	}
+}
+
+func (b *LinkBlocklist) Name() string {
+	return "links"
+}
+
+func (b *LinkBlocklist) Reload(ctx context.Context) (int, error) {
+	list, err := b.source.Load(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	domains := make(map[string]bool, len(list))
+	for _, domain := range list {
+		domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+		if domain != "" {
+			domains[domain] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.domains = domains
+	b.mu.Unlock()
+
+	return len(domains), nil
+}
+
+func (b *LinkBlocklist) blocked(host string) bool {
+	for host != "" {
+		if b.domains[host] {
+			return true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return false
+		}
+		host = parent
+	}
+	return false
+}
+
+func (b *LinkBlocklist) Moderate(ctx context.Context, body string) (Verdict, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	masked := body
+	var hosts []string
+	for _, link := range links(body) {
+		host := linkHost(link)
+		if !b.blocked(host) {
+			continue
+		}
+		hosts = append(hosts, host)
+		masked = strings.Replace(masked, link, mask, 1)
+	}
+	if len(hosts) == 0 {
+		return Verdict{Action: ActionAllow}, nil
+	}
+
+	return Verdict{
+		Action: b.action,
+		Body:   masked,
+		Reason: "blocked domains: " + strings.Join(hosts, ", "),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var leetReplacer = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"7", "t",
+	"8", "b",
+	"@", "a",
+	"$", "s",
+)
+
+// Normalize folds a word into the form used for matching: lower case,
+// diacritics removed, common leetspeak substitutions undone and anything
+// that is not a letter dropped.
+func Normalize(word string) string {
+	return normalize(word, leetReplacer)
+}
+
+// "1" stands in for both "i" and "l", so words containing it are also
+// tried with the second reading.
+var leetReplacerAlt = strings.NewReplacer("1", "l")
+
+// forms returns every normalized reading of word.
+func forms(word string) []string {
+	if !strings.Contains(word, "1") {
+		return []string{Normalize(word)}
+	}
+	return []string{Normalize(word), Normalize(leetReplacerAlt.Replace(word))}
+}
+
+func normalize(word string, leet *strings.Replacer) string {
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(stripMarks, strings.ToLower(word))
+	if err != nil {
+		folded = strings.ToLower(word)
+	}
+	folded = leet.Replace(folded)
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, folded)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '@' || r == '$'
+}
+
+type span struct {
+	start, end int
+}
+
+// words returns the byte spans of every word in s. Punctuation and
+// whitespace separate words, so "kerfuffle!" yields "kerfuffle".
+func words(s string) []span {
+	var spans []span
+	start := -1
+	for i, r := range s {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(s)})
+	}
+
+	return spans
+}
@@ -0,0 +1,116 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+type Action string
+
+const (
+	ActionAllow Action = "allow"
+	// ActionFlag lets the chirp through unchanged but marks it for review.
+	ActionFlag   Action = "flag"
+	ActionMask   Action = "mask"
+	ActionHold   Action = "hold"
+	ActionReject Action = "reject"
+)
+
+func ParseAction(s string) (Action, error) {
+	switch action := Action(s); action {
+	case ActionAllow, ActionFlag, ActionMask, ActionHold, ActionReject:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown moderation action %q", s)
+}
+
+// Verdict is the outcome of a single stage. Body is only read when the
+// action is ActionMask.
+type Verdict struct {
+	Action Action
+	Body   string
+	Reason string
+}
+
+type Stage interface {
+	Name() string
+	Moderate(ctx context.Context, body string) (Verdict, error)
+}
+
+// Decision is the combined outcome of every stage in a Pipeline.
+type Decision struct {
+	Body     string
+	Flagged  bool
+	Held     bool
+	Rejected bool
+	Reasons  []string
+}
+
+// Pipeline runs a chirp body through its stages in order. Masks are applied
+// before the next stage sees the body, a reject stops the pipeline and a
+// hold is remembered while the remaining stages still get a chance to
+// reject.
+type Pipeline struct {
+	stages []Stage
+}
+
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+func (p *Pipeline) Run(ctx context.Context, body string) (Decision, error) {
+	decision := Decision{Body: body}
+	for _, stage := range p.stages {
+		verdict, err := stage.Moderate(ctx, decision.Body)
+		if err != nil {
+			return Decision{}, fmt.Errorf("moderation stage %s: %w", stage.Name(), err)
+		}
+		if verdict.Action == ActionAllow {
+			continue
+		}
+		if verdict.Reason != "" {
+			decision.Reasons = append(decision.Reasons, stage.Name()+": "+verdict.Reason)
+		}
+
+		switch verdict.Action {
+		case ActionFlag:
+			decision.Flagged = true
+		case ActionMask:
+			decision.Body = verdict.Body
+		case ActionHold:
+			decision.Held = true
+		case ActionReject:
+			decision.Rejected = true
+			return decision, nil
+		default:
+			return Decision{}, fmt.Errorf("moderation stage %s: unknown action %q", stage.Name(), verdict.Action)
+		}
+	}
+
+	return decision, nil
+}
+
+type reloader interface {
+	Reload(ctx context.Context) (int, error)
+}
+
+// Reload refreshes every stage backed by a Source and returns the number of
+// entries loaded per stage.
+func (p *Pipeline) Reload(ctx context.Context) (map[string]int, error) {
+	counts := map[string]int{}
+	for _, stage := range p.stages {
+		r, ok := stage.(reloader)
+		if !ok {
+			continue
+		}
+		count, err := r.Reload(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reloading %s: %w", stage.Name(), err)
+		}
+		log.Printf("moderation: loaded %d entries for %s", count, stage.Name())
+		counts[stage.Name()] = count
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,166 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPipelineRun(t *testing.T) {
+	ctx := context.Background()
+
+	words := NewFilter(SourceFunc(func(ctx context.Context) ([]string, error) {
+		return []string{"kerfuffle"}, nil
+	}), ActionMask)
+	blocklist := NewLinkBlocklist(SourceFunc(func(ctx context.Context) ([]string, error) {
+		return []string{"spam.example"}, nil
+	}), ActionReject)
+	pipeline := NewPipeline(words, blocklist, DefaultSpamHeuristics())
+	if _, err := pipeline.Reload(ctx); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	classifier := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params struct {
+			Body string `json:"body"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+
+		response := map[string]string{"action": "allow", "reason": "stand-in"}
+		switch {
+		case strings.Contains(params.Body, "suspicious"):
+			response["action"] = "hold"
+		case strings.Contains(params.Body, "rude"):
+			response["action"] = "mask"
+			response["body"] = strings.ReplaceAll(params.Body, "rude", "****")
+		case strings.Contains(params.Body, "rewrite"):
+			response["action"] = "mask"
+			response["body"] = strings.Repeat("x", 200)
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer classifier.Close()
+	withClassifier := NewPipeline(words, NewHTTPClassifier(classifier.URL))
+
+	unreachable := NewHTTPClassifier("http://127.0.0.1:0")
+	withDownClassifier := NewPipeline(unreachable)
+
+	tests := []struct {
+		name         string
+		pipeline     *Pipeline
+		input        string
+		expectedBody string
+		expectedHeld bool
+		expectedRej  bool
+	}{
+		{
+			name:         "Clean chirp",
+			pipeline:     pipeline,
+			input:        "Good morning",
+			expectedBody: "Good morning",
+		},
+		{
+			name:         "Masked word",
+			pipeline:     pipeline,
+			input:        "What a kerfuffle",
+			expectedBody: "What a ****",
+		},
+		{
+			name:        "Blocked subdomain",
+			pipeline:    pipeline,
+			input:       "Visit https://win.spam.example/now",
+			expectedRej: true,
+		},
+		{
+			name:        "Blocked bare domain",
+			pipeline:    pipeline,
+			input:       "Visit spam.example/now",
+			expectedRej: true,
+		},
+		{
+			name:         "Bare domains count as links",
+			pipeline:     pipeline,
+			input:        "BUY NOW BUY NOW!!!!!!!! a.io b.io/x c.io:8080",
+			expectedBody: "BUY NOW BUY NOW!!!!!!!! a.io b.io/x c.io:8080",
+			expectedHeld: true,
+		},
+		{
+			name:         "Spam signals",
+			pipeline:     pipeline,
+			input:        "BUY NOW BUY NOW!!!!!!!! http://a.io http://b.io http://c.io",
+			expectedBody: "BUY NOW BUY NOW!!!!!!!! http://a.io http://b.io http://c.io",
+			expectedHeld: true,
+		},
+		{
+			name:         "Classifier sees masked body",
+			pipeline:     withClassifier,
+			input:        "suspicious kerfuffle",
+			expectedBody: "suspicious ****",
+			expectedHeld: true,
+		},
+		{
+			name:         "Classifier masks",
+			pipeline:     withClassifier,
+			input:        "a rude reply",
+			expectedBody: "a **** reply",
+		},
+		{
+			name:         "Classifier rewrites the body",
+			pipeline:     withClassifier,
+			input:        "please rewrite this",
+			expectedBody: "please rewrite this",
+			expectedHeld: true,
+		},
+		{
+			name:         "Classifier unreachable",
+			pipeline:     withDownClassifier,
+			input:        "Good morning",
+			expectedBody: "Good morning",
+			expectedHeld: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := tt.pipeline.Run(ctx, tt.input)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if decision.Rejected != tt.expectedRej {
+				t.Errorf("Run() rejected = %v, expected %v (%v)", decision.Rejected, tt.expectedRej, decision.Reasons)
+			}
+			if tt.expectedRej {
+				return
+			}
+			if decision.Body != tt.expectedBody {
+				t.Errorf("Run() body = %q, expected %q", decision.Body, tt.expectedBody)
+			}
+			if decision.Held != tt.expectedHeld {
+				t.Errorf("Run() held = %v, expected %v (%v)", decision.Held, tt.expectedHeld, decision.Reasons)
+			}
+		})
+	}
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "see https://example.com/a?b=c", expected: []string{"https://example.com/a?b=c"}},
+		{input: "see www.example.com", expected: []string{"www.example.com"}},
+		{input: "see evil.example/path and sub.evil.example:8080", expected: []string{"evil.example/path", "sub.evil.example:8080"}},
+		{input: "ends a sentence with example.com.", expected: []string{"example.com"}},
+		{input: "e.g. version 1.2.3 or v1.2", expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := links(tt.input); !slices.Equal(got, tt.expected) {
+				t.Errorf("links(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+)
+
+// Source provides the list of banned words for a Filter.
+type Source interface {
+	Load(ctx context.Context) ([]string, error)
+}
+
+// SourceFunc adapts a function, such as a database query, to a Source.
+type SourceFunc func(ctx context.Context) ([]string, error)
+
+func (f SourceFunc) Load(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// FileSource reads one word per line. Blank lines and lines starting with
+// '#' are ignored.
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Load(ctx context.Context) ([]string, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+
+	return words, scanner.Err()
+}
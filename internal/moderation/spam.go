@@ -0,0 +1,106 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SpamHeuristics scores a chirp on a few cheap signals and answers with
+// Action once the score reaches Threshold.
+type SpamHeuristics struct {
+	Action    Action
+	Threshold int
+	// MaxLinks is the number of links a chirp may carry before it counts
+	// as a spam signal.
+	MaxLinks int
+	// MaxRepeats is the longest run of one repeated character or word
+	// allowed before it counts as a spam signal.
+	MaxRepeats int
+}
+
+func DefaultSpamHeuristics() SpamHeuristics {
+	return SpamHeuristics{
+		Action:     ActionHold,
+		Threshold:  2,
+		MaxLinks:   2,
+		MaxRepeats: 5,
+	}
+}
+
+func (s SpamHeuristics) Name() string {
+	return "spam"
+}
+
+func (s SpamHeuristics) Moderate(ctx context.Context, body string) (Verdict, error) {
+	var signals []string
+	if n := len(links(body)); n > s.MaxLinks {
+		signals = append(signals, fmt.Sprintf("%d links", n))
+	}
+	if shouting(body) {
+		signals = append(signals, "all caps")
+	}
+	if longestRuneRun(body) > s.MaxRepeats {
+		signals = append(signals, "repeated characters")
+	}
+	if longestWordRun(body) > s.MaxRepeats {
+		signals = append(signals, "repeated words")
+	}
+
+	if len(signals) < s.Threshold {
+		return Verdict{Action: ActionAllow}, nil
+	}
+
+	return Verdict{
+		Action: s.Action,
+		Body:   body,
+		Reason: strings.Join(signals, ", "),
+	}, nil
+}
+
+// shouting reports whether a chirp with a reasonable amount of letters has
+// no lower case letters at all.
+func shouting(s string) bool {
+	letters := 0
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsUpper(r) {
+			letters++
+		}
+	}
+	return letters >= 10
+}
+
+func longestRuneRun(s string) int {
+	longest, run := 0, 0
+	var prev rune
+	for _, r := range s {
+		if r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+func longestWordRun(s string) int {
+	longest, run := 0, 0
+	prev := ""
+	for _, w := range words(s) {
+		word := Normalize(s[w.start:w.end])
+		if word != "" && word == prev {
+			run++
+		} else {
+			run = 1
+		}
+		prev = word
+		longest = max(longest, run)
+	}
+	return longest
+}
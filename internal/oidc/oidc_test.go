@@ -0,0 +1,224 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeProvider is a stand-in OpenID Connect provider. It signs everyone in
+// as the same user and remembers the PKCE challenge and nonce of every
+// authorization request, like a real provider would.
+type fakeProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	secret   string
+	email    string
+	verified bool
+
+	mu    sync.Mutex
+	codes map[string]url.Values
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	f := &fakeProvider{
+		key:      key,
+		clientID: "chirpy",
+		secret:   "chirpy-secret",
+		email:    "someone@example.com",
+		verified: true,
+		codes:    map[string]url.Values{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.server.URL,
+			"authorization_endpoint": f.server.URL + "/authorize",
+			"token_endpoint":         f.server.URL + "/token",
+			"jwks_uri":               f.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "key-1",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		code := r.URL.Query().Get("state") + "-code"
+		f.mu.Lock()
+		f.codes[code] = r.URL.Query()
+		f.mu.Unlock()
+		http.Redirect(w, r, r.URL.Query().Get("redirect_uri")+"?code="+code, http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		r.ParseForm()
+		f.mu.Lock()
+		request, ok := f.codes[r.PostForm.Get("code")]
+		delete(f.codes, r.PostForm.Get("code"))
+		f.mu.Unlock()
+		if clientID != f.clientID || secret != f.secret {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if !ok || !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), request.Get("code_challenge")) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "opaque",
+			"token_type":   "Bearer",
+			"id_token":     f.idToken(t, f.claims(request.Get("nonce"))),
+		})
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeProvider) claims(nonce string) Claims {
+	now := time.Now()
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    f.server.URL,
+			Subject:   "user-123",
+			Audience:  jwt.ClaimStrings{f.clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:         nonce,
+		Email:         f.email,
+		EmailVerified: f.verified,
+	}
+}
+
+func (f *fakeProvider) idToken(t *testing.T, claims Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(f.key)
+	if err != nil {
+		t.Fatalf("Error signing ID token: %v", err)
+	}
+	return signed
+}
+
+// authorize follows the authorization URL like a browser would and
+// returns the code the provider redirects back with.
+func authorize(t *testing.T, authURL string) string {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Error following authorization URL: %v", err)
+	}
+	res.Body.Close()
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect: %v", err)
+	}
+	return location.Query().Get("code")
+}
+
+func TestCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	provider := NewProvider(fake.server.URL, fake.clientID, fake.secret, "http://localhost:8080/callback")
+	verifier := strings.Repeat("v", 43)
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	claims, err := provider.Exchange(ctx, authorize(t, authURL), verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if claims.Subject != "user-123" || claims.Email != fake.email || !claims.EmailVerified {
+		t.Errorf("Exchange() claims = %+v, expected user-123 with a verified %s", claims, fake.email)
+	}
+
+	authURL, _ = provider.AuthCodeURL(ctx, "state-2", "nonce-2", verifier)
+	if _, err := provider.Exchange(ctx, authorize(t, authURL), strings.Repeat("w", 43), "nonce-2"); err == nil {
+		t.Errorf("Exchange() with the wrong code verifier succeeded")
+	}
+
+	authURL, _ = provider.AuthCodeURL(ctx, "state-3", "nonce-3", verifier)
+	if _, err := provider.Exchange(ctx, authorize(t, authURL), verifier, "other-nonce"); !errors.Is(err, ErrNonceMismatch) {
+		t.Errorf("Exchange() with another login's nonce error = %v, expected %v", err, ErrNonceMismatch)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeProvider(t)
+	provider := NewProvider(fake.server.URL, fake.clientID, fake.secret, "http://localhost:8080/callback")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		edit      func(*Claims)
+		signWith  *rsa.PrivateKey
+		expectErr bool
+	}{
+		{name: "Valid token", edit: func(*Claims) {}, expectErr: false},
+		{name: "Other audience", edit: func(c *Claims) { c.Audience = jwt.ClaimStrings{"someone-else"} }, expectErr: true},
+		{name: "Other issuer", edit: func(c *Claims) { c.Issuer = "https://evil.example.com" }, expectErr: true},
+		{name: "Expired", edit: func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }, expectErr: true},
+		{name: "Signed with an unknown key", edit: func(*Claims) {}, signWith: otherKey, expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := fake.claims("nonce")
+			tt.edit(&claims)
+			token := fake.idToken(t, claims)
+			if tt.signWith != nil {
+				unsigned := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+				unsigned.Header["kid"] = "key-1"
+				token, _ = unsigned.SignedString(tt.signWith)
+			}
+
+			_, err := provider.VerifyIDToken(ctx, token, "nonce")
+			if (err != nil) != tt.expectErr {
+				t.Errorf("VerifyIDToken() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := NewProvider(fake.server.URL+"/other", fake.clientID, fake.secret, "http://localhost:8080/callback")
+	if _, err := provider.AuthCodeURL(context.Background(), "state", "nonce", strings.Repeat("v", 43)); err == nil {
+		t.Errorf("AuthCodeURL() succeeded for an issuer whose metadata names another")
+	}
+}
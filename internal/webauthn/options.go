@@ -0,0 +1,92 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"time"
+)
+
+// The options types mirror PublicKeyCredentialCreationOptions and
+// PublicKeyCredentialRequestOptions, with binary fields base64url encoded
+// for the client to decode before calling the browser API.
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type CreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+}
+
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int                    `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+func descriptors(credentialIDs [][]byte) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, len(credentialIDs))
+	for i, id := range credentialIDs {
+		list[i] = CredentialDescriptor{Type: "public-key", ID: base64.RawURLEncoding.EncodeToString(id)}
+	}
+	return list
+}
+
+// CreationOptions builds the options for registering a passkey for a
+// user, excluding the credentials they already have.
+func (rp RelyingParty) CreationOptions(challenge, userHandle []byte, name string, existing [][]byte, timeout time.Duration) CreationOptions {
+	options := CreationOptions{
+		Challenge:          base64.RawURLEncoding.EncodeToString(challenge),
+		Timeout:            int(timeout.Milliseconds()),
+		Attestation:        "none",
+		ExcludeCredentials: descriptors(existing),
+	}
+	options.RP.ID = rp.ID
+	options.RP.Name = rp.Name
+	options.User.ID = base64.RawURLEncoding.EncodeToString(userHandle)
+	options.User.Name = name
+	options.User.DisplayName = name
+	for _, alg := range SupportedAlgorithms {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int    `json:"alg"`
+		}{Type: "public-key", Alg: alg})
+	}
+	options.AuthenticatorSelection.ResidentKey = "preferred"
+	options.AuthenticatorSelection.UserVerification = "preferred"
+	return options
+}
+
+// RequestOptions builds the options for logging in. An empty allow list
+// lets the user pick any discoverable passkey for the site.
+func (rp RelyingParty) RequestOptions(challenge []byte, allowed [][]byte, timeout time.Duration) RequestOptions {
+	return RequestOptions{
+		Challenge:        base64.RawURLEncoding.EncodeToString(challenge),
+		RPID:             rp.ID,
+		Timeout:          int(timeout.Milliseconds()),
+		AllowCredentials: descriptors(allowed),
+		UserVerification: "preferred",
+	}
+}
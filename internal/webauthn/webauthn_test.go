@@ -0,0 +1,194 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"testing"
+)
+
+// encodeCBOR is the encoding half of the CBOR subset, for building what an
+// authenticator would send.
+func encodeCBOR(v any) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		}
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case map[any]any:
+		var keys []any
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(encodeCBOR(keys[i])) < string(encodeCBOR(keys[j])) })
+		out := head(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(out, encodeCBOR(k)...)
+			out = append(out, encodeCBOR(v[k])...)
+		}
+		return out
+	}
+	panic("unsupported type")
+}
+
+// softAuthenticator is a software passkey holding one P-256 key.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	// verifyUser makes assertions claim a PIN or biometric check.
+	verifyUser bool
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	return &softAuthenticator{key: key, credentialID: NewChallenge()[:16]}
+}
+
+func (a *softAuthenticator) clientData(ceremony, origin string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	return data
+}
+
+func (a *softAuthenticator) authData(rpID string, flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if !attested {
+		return data
+	}
+
+	data = append(data, make([]byte, 16)...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+	data = append(data, a.credentialID...)
+	return append(data, encodeCBOR(map[any]any{
+		1:  2,
+		3:  AlgES256,
+		-1: 1,
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})...)
+}
+
+func (a *softAuthenticator) create(rpID, origin string, challenge []byte) (clientDataJSON, attestationObject []byte) {
+	clientDataJSON = a.clientData("webauthn.create", origin, challenge)
+	attestationObject = encodeCBOR(map[any]any{
+		"fmt":      "none",
+		"attStmt":  map[any]any{},
+		"authData": a.authData(rpID, flagUserPresent|flagAttested, true),
+	})
+	return clientDataJSON, attestationObject
+}
+
+func (a *softAuthenticator) get(rpID, origin string, challenge []byte) (clientDataJSON, authData, signature []byte) {
+	a.signCount++
+	clientDataJSON = a.clientData("webauthn.get", origin, challenge)
+	flags := byte(flagUserPresent)
+	if a.verifyUser {
+		flags |= flagUserVerified
+	}
+	authData = a.authData(rpID, flags, false)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, _ = ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	return clientDataJSON, authData, signature
+}
+
+func TestPasskeyCeremonies(t *testing.T) {
+	rp := RelyingParty{ID: "localhost", Name: "Chirpy", Origin: "http://localhost:8080"}
+	authenticator := newSoftAuthenticator(t)
+
+	challenge := NewChallenge()
+	clientDataJSON, attestationObject := authenticator.create(rp.ID, rp.Origin, challenge)
+	if got, err := ChallengeFromClientData(clientDataJSON); err != nil || string(got) != string(challenge) {
+		t.Fatalf("ChallengeFromClientData() = %x, %v, expected %x", got, err, challenge)
+	}
+	credential, err := rp.VerifyRegistration(clientDataJSON, attestationObject, challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration() error = %v", err)
+	}
+	if string(credential.ID) != string(authenticator.credentialID) {
+		t.Errorf("VerifyRegistration() credential ID = %x, expected %x", credential.ID, authenticator.credentialID)
+	}
+
+	tests := []struct {
+		name        string
+		rp          RelyingParty
+		challenge   []byte
+		answered    []byte
+		tamper      bool
+		replay      bool
+		verifyUser  bool
+		expectedErr error
+	}{
+		{name: "Valid assertion", rp: rp, verifyUser: true, expectedErr: nil},
+		{name: "User presence only", rp: rp, verifyUser: false, expectedErr: nil},
+		{name: "Other challenge", rp: rp, answered: NewChallenge(), expectedErr: ErrChallengeMismatch},
+		{name: "Phishing origin", rp: RelyingParty{ID: rp.ID, Origin: "https://chirpy.evil"}, expectedErr: ErrOriginMismatch},
+		{name: "Other relying party", rp: RelyingParty{ID: "chirpy.evil", Origin: rp.Origin}, expectedErr: ErrRPIDMismatch},
+		{name: "Tampered signature", rp: rp, tamper: true, expectedErr: ErrInvalidSignature},
+		{name: "Replayed counter", rp: rp, replay: true, expectedErr: ErrCounterRegressed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := NewChallenge()
+			answered := challenge
+			if tt.answered != nil {
+				answered = tt.answered
+			}
+			if tt.replay {
+				authenticator.signCount = credential.SignCount - 1
+			}
+			authenticator.verifyUser = tt.verifyUser
+			clientDataJSON, authData, signature := authenticator.get(rp.ID, tt.rp.Origin, answered)
+			if tt.tamper {
+				signature[len(signature)-1] ^= 0xff
+			}
+
+			verifier := rp
+			verifier.ID = tt.rp.ID
+			assertion, err := verifier.VerifyAssertion(clientDataJSON, authData, signature, challenge, credential)
+			if err != tt.expectedErr {
+				t.Fatalf("VerifyAssertion() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if err == nil {
+				if assertion.SignCount != authenticator.signCount {
+					t.Errorf("VerifyAssertion() sign count = %d, expected %d", assertion.SignCount, authenticator.signCount)
+				}
+				if assertion.UserVerified != tt.verifyUser {
+					t.Errorf("VerifyAssertion() user verified = %v, expected %v", assertion.UserVerified, tt.verifyUser)
+				}
+				credential.SignCount = assertion.SignCount
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+)
+
+// newKeyring loads the JWT signing keys from JWT_KEYS_DIR, signing with
+// JWT_SIGNING_KID. Without a key directory a throwaway key is generated,
+// which is fine for development but logs everyone out on restart.
+// JWT_LEEWAY (a Go duration such as "30s") tolerates clock skew between
+// the servers issuing and checking tokens. HS256 tokens signed with SECRET
+// before the switch to asymmetric keys are only accepted if
+// JWT_LEGACY_SECRET_UNTIL (an RFC 3339 time) is set and still ahead.
+func newKeyring(secret string) (*auth.Keyring, error) {
+	var keys *auth.Keyring
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		var err error
+		keys, err = auth.LoadKeyring(dir, os.Getenv("JWT_SIGNING_KID"))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Printf("JWT_KEYS_DIR is not set, signing access tokens with a temporary key")
+		key, err := auth.GenerateSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = auth.NewKeyring()
+		if err := keys.Rotate("dev-"+time.Now().UTC().Format("20060102150405"), key, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if leeway := os.Getenv("JWT_LEEWAY"); leeway != "" {
+		var err error
+		keys.Leeway, err = time.ParseDuration(leeway)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_LEEWAY: %w", err)
+		}
+	}
+
+	if until := os.Getenv("JWT_LEGACY_SECRET_UNTIL"); until != "" && secret != "" {
+		cutoff, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_LEGACY_SECRET_UNTIL: %w", err)
+		}
+		if time.Now().Before(cutoff) {
+			keys.AddLegacySecret(secret, cutoff)
+		}
+	}
+	return keys, nil
+}
+
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"keys": cfg.keys.JWKS(),
+	})
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type List struct {
+	database.List
+	Members []uuid.UUID `json:"members"`
+}
+
+// readableList loads a list for viewer, who is uuid.Nil for anonymous
+// requests. Private lists are reported as missing to everyone but their
+// owner.
+func (cfg *apiConfig) readableList(ctx context.Context, id string, viewer uuid.UUID) (database.List, int, error) {
+	listID, err := uuid.Parse(id)
+	if err != nil {
+		return database.List{}, http.StatusBadRequest, errors.New("Invalid UUID:" + err.Error())
+	}
+
+	list, err := cfg.db.GetList(ctx, listID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && list.Private && list.UserID != viewer) {
+		return database.List{}, http.StatusNotFound, errors.New("List not found")
+	}
+	if err != nil {
+		return database.List{}, http.StatusInternalServerError, err
+	}
+
+	return list, 0, nil
+}
+
+// ownList loads a list and checks that it belongs to userID.
+func (cfg *apiConfig) ownList(ctx context.Context, id string, userID uuid.UUID) (database.List, int, error) {
+	list, status, err := cfg.readableList(ctx, id, userID)
+	if err != nil {
+		return database.List{}, status, err
+	}
+	if list.UserID != userID {
+		return database.List{}, http.StatusForbidden, errors.New("You can only change your own lists")
+	}
+	return list, 0, nil
+}
+
+func (cfg *apiConfig) listResponse(ctx context.Context, list database.List) (List, error) {
+	members, err := cfg.db.GetListMembers(ctx, list.ID)
+	if err != nil {
+		return List{}, err
+	}
+	if members == nil {
+		members = []uuid.UUID{}
+	}
+	return List{List: list, Members: members}, nil
+}
+
+type listParameters struct {
+	Name    string `json:"name"`
+	Private bool   `json:"private"`
+}
+
+func decodeList(r *http.Request) (listParameters, error) {
+	decoder := json.NewDecoder(r.Body)
+	params := listParameters{}
+	if err := decoder.Decode(&params); err != nil {
+		return listParameters{}, errors.New("Invalid JSON:" + err.Error())
+	}
+
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > 50 {
+		return listParameters{}, errors.New("List name must be between 1 and 50 characters")
+	}
+	return params, nil
+}
+
+func (cfg *apiConfig) handlerListsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	lists, err := cfg.db.GetListsByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if lists == nil {
+		lists = []database.List{}
+	}
+
+	respondWithJSON(w, http.StatusOK, lists)
+}
+
+func (cfg *apiConfig) handlerListCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	params, err := decodeList(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	list, err := cfg.db.CreateList(r.Context(), database.CreateListParams{
+		UserID:  userID,
+		Name:    params.Name,
+		Private: params.Private,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "A list with that name already exists")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, List{List: list, Members: []uuid.UUID{}})
+}
+
+func (cfg *apiConfig) handlerListGet(w http.ResponseWriter, r *http.Request) {
+	viewer, err := cfg.optionalUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	list, status, err := cfg.readableList(r.Context(), r.PathValue("id"), viewer)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	response, err := cfg.listResponse(r.Context(), list)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerListUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	list, status, err := cfg.ownList(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	params, err := decodeList(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	list, err = cfg.db.UpdateList(r.Context(), database.UpdateListParams{
+		Name:    params.Name,
+		Private: params.Private,
+		ID:      list.ID,
+		UserID:  userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "List not found")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "A list with that name already exists")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response, err := cfg.listResponse(r.Context(), list)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerListDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	list, status, err := cfg.ownList(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	if _, err := cfg.db.DeleteList(r.Context(), database.DeleteListParams{
+		ID:     list.ID,
+		UserID: userID,
+	}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListMemberAdd(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	list, status, err := cfg.ownList(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	type parameters struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	added, err := cfg.db.AddListMember(r.Context(), database.AddListMemberParams{
+		ListID: list.ID,
+		UserID: params.UserID,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if added == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (cfg *apiConfig) handlerListMemberRemove(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	list, status, err := cfg.ownList(r.Context(), r.PathValue("id"), userID)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	memberID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	removed, err := cfg.db.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, "User is not in this list")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListTimeline(w http.ResponseWriter, r *http.Request) {
+	viewer, err := cfg.optionalUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	list, status, err := cfg.readableList(r.Context(), r.PathValue("id"), viewer)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	cursor, limit, err := timelineParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	members, err := cfg.db.GetListMembers(r.Context(), list.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response, err := cfg.timeline(r.Context(), members, viewer, cursor, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
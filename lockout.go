@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// An address that fails maxLoginIPFailures logins within loginIPWindow,
+	// whichever accounts it tries, can't log in for loginIPLockout.
+	loginIPWindow      = 15 * time.Minute
+	maxLoginIPFailures = 50
+	loginIPLockout     = 15 * time.Minute
+	// loginEmailFailuresTTL is how long failed logins for an email address
+	// are remembered once it is no longer locked.
+	loginEmailFailuresTTL = 24 * time.Hour
+)
+
+var (
+	errIncorrectLogin    = errors.New("Incorrect email or password")
+	errIncorrectPassword = errors.New("Incorrect password")
+)
+
+type loginThrottledError struct {
+	wait time.Duration
+}
+
+func (e loginThrottledError) Error() string {
+	return fmt.Sprintf("Too many failed logins, try again in %d seconds", retryAfterSeconds(e.wait))
+}
+
+func retryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+// loginEmail is the key failed logins for email are counted under.
+func loginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLogin returns the user with email if password is theirs. Failures
+// count against both the email address and ip: addresses have to wait
+// longer and longer between attempts and are then locked for a while, and
+// IPs guessing across many accounts are locked too. Whether the account
+// exists makes no difference to the answer or to how long it takes: the
+// password is always run through bcrypt, throttled or not, and unknown
+// emails are throttled like any other.
+func (cfg *apiConfig) checkLogin(ctx context.Context, ip, email, password string) (database.User, error) {
+	wait, err := cfg.reserveLoginAttempt(ctx, ip, loginEmail(email))
+	if err != nil {
+		return database.User{}, err
+	}
+
+	var correct bool
+	user, err := cfg.db.GetUser(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		correct = auth.CheckNoPassword(password)
+	} else if err != nil {
+		return database.User{}, err
+	} else {
+		correct = auth.CheckPasswordHash(password, user.HashedPassword)
+	}
+
+	if wait > 0 {
+		return database.User{}, loginThrottledError{wait: wait}
+	}
+	if !correct {
+		return database.User{}, errIncorrectLogin
+	}
+	if err := cfg.forgetLoginAttempt(ctx, ip, loginEmail(email)); err != nil {
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+// checkPassword asks a signed in user for their password again before
+// something sensitive, under the same limits as logging in.
+func (cfg *apiConfig) checkPassword(ctx context.Context, ip string, user database.User, password string) error {
+	wait, err := cfg.reserveLoginAttempt(ctx, ip, loginEmail(user.Email))
+	if err != nil {
+		return err
+	}
+	correct := auth.CheckPasswordHash(password, user.HashedPassword)
+	if wait > 0 {
+		return loginThrottledError{wait: wait}
+	}
+	if !correct {
+		return errIncorrectPassword
+	}
+	return cfg.forgetLoginAttempt(ctx, ip, loginEmail(user.Email))
+}
+
+func lockedFor(lockedUntil sql.NullTime) time.Duration {
+	if !lockedUntil.Valid {
+		return 0
+	}
+	return lockedUntil.Time.Sub(time.Now().UTC())
+}
+
+// reserveLoginAttempt counts a password attempt for email from ip as a
+// failure before the password is checked, and locks whichever has now
+// failed too often. Both rows stay locked until this is committed, so
+// attempts made in parallel can't all get in before the first failure is
+// counted. It returns how long to wait if the attempt isn't allowed.
+func (cfg *apiConfig) reserveLoginAttempt(ctx context.Context, ip, email string) (time.Duration, error) {
+	now := time.Now().UTC()
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Rows are always locked email first, then address, so two attempts
+	// can't deadlock.
+	err = qtx.CreateLoginEmailFailures(ctx, database.CreateLoginEmailFailuresParams{Email: email, Now: now})
+	if err != nil {
+		return 0, err
+	}
+	emailFailures, err := qtx.GetLoginEmailFailuresForUpdate(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+	err = qtx.CreateLoginIPFailures(ctx, database.CreateLoginIPFailuresParams{IpAddress: ip, Now: now})
+	if err != nil {
+		return 0, err
+	}
+	ipFailures, err := qtx.GetLoginIPFailuresForUpdate(ctx, ip)
+	if err != nil {
+		return 0, err
+	}
+	if wait := max(lockedFor(emailFailures.LockedUntil), lockedFor(ipFailures.LockedUntil)); wait > 0 {
+		return wait, tx.Commit()
+	}
+
+	failures, err := qtx.RecordLoginIPFailure(ctx, database.RecordLoginIPFailureParams{
+		WindowStart: now.Add(-loginIPWindow),
+		Now:         now,
+		IpAddress:   ip,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if failures > maxLoginIPFailures {
+		err := qtx.LockLoginIP(ctx, database.LockLoginIPParams{
+			IpAddress:   ip,
+			LockedUntil: sql.NullTime{Time: now.Add(loginIPLockout), Valid: true},
+		})
+		if err != nil {
+			return 0, err
+		}
+		log.Printf("login: locked out %s after %d failed logins", ip, failures-1)
+		return loginIPLockout, tx.Commit()
+	}
+
+	failures, err = qtx.RecordLoginEmailFailure(ctx, database.RecordLoginEmailFailureParams{
+		Now:   now,
+		Email: email,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if wait := auth.LoginBackoff(int(failures)); wait > 0 {
+		err := qtx.LockLoginEmail(ctx, database.LockLoginEmailParams{
+			Email:       email,
+			LockedUntil: sql.NullTime{Time: now.Add(wait), Valid: true},
+		})
+		if err != nil {
+			return 0, err
+		}
+		if failures == auth.LockoutThreshold {
+			log.Printf("login: locked %s after %d failed logins", email, failures)
+		}
+	}
+	return 0, tx.Commit()
+}
+
+// forgetLoginAttempt takes back an attempt reserved by reserveLoginAttempt
+// once the password turned out to be right. Earlier failures for the
+// email no longer count, but those from the address still do.
+func (cfg *apiConfig) forgetLoginAttempt(ctx context.Context, ip, email string) error {
+	if err := cfg.db.DeleteLoginEmailFailures(ctx, email); err != nil {
+		return err
+	}
+	return cfg.db.ForgetLoginIPFailure(ctx, ip)
+}
+
+// purgeLoginFailures forgets failed logins that no longer count towards a
+// lockout.
+func (cfg *apiConfig) purgeLoginFailures(ctx context.Context) {
+	now := time.Now().UTC()
+	err := cfg.db.DeleteStaleLoginIPFailures(ctx, database.DeleteStaleLoginIPFailuresParams{
+		WindowStart: now.Add(-loginIPWindow),
+		Now:         now,
+	})
+	if err != nil {
+		log.Printf("scheduler: deleting stale login failures: %s", err)
+	}
+	err = cfg.db.DeleteStaleLoginEmailFailures(ctx, database.DeleteStaleLoginEmailFailuresParams{
+		LastFailedBefore: now.Add(-loginEmailFailuresTTL),
+		Now:              now,
+	})
+	if err != nil {
+		log.Printf("scheduler: deleting stale login failures: %s", err)
+	}
+}
+
+func respondLoginError(w http.ResponseWriter, err error) {
+	var throttled loginThrottledError
+	if errors.As(err, &throttled) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(throttled.wait)))
+		respondWithError(w, http.StatusTooManyRequests, throttled.Error())
+		return
+	}
+	if errors.Is(err, errIncorrectLogin) || errors.Is(err, errIncorrectPassword) {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, err.Error())
+}
+
+// handlerUserUnlock lifts a user's login lockout and forgets their failed
+// logins.
+func (cfg *apiConfig) handlerUserUnlock(w http.ResponseWriter, r *http.Request) {
+	adminID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err := cfg.db.DeleteLoginEmailFailures(r.Context(), loginEmail(user.Email)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf("login: %s unlocked user %s", adminID, userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerLoginIPUnlock lifts the lockout of an address, e.g. an office
+// whose users share one.
+func (cfg *apiConfig) handlerLoginIPUnlock(w http.ResponseWriter, r *http.Request) {
+	adminID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	ip := r.PathValue("ip")
+	unlocked, err := cfg.db.DeleteLoginIPFailures(r.Context(), ip)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if unlocked == 0 {
+		respondWithError(w, http.StatusNotFound, "No failed logins from that address")
+		return
+	}
+	log.Printf("login: %s unlocked %s", adminID, ip)
+
+	w.WriteHeader(http.StatusNoContent)
+}
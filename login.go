@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/dipzza/bootdev_chirpy/internal/mailer"
+)
+
+const (
+	magicLinkLifetime = 15 * time.Minute
+	magicDeviceCookie = "chirpy_magic_device"
+	clientHeader      = "Chirpy-Client"
+)
+
+// newTokenLifetimes reads ACCESS_TOKEN_LIFETIME and REFRESH_TOKEN_LIFETIME,
+// which default to an hour and 60 days. CLIENT_TOKEN_LIFETIMES overrides
+// them for the clients that name themselves in the Chirpy-Client header,
+// as a list like "web=15m/24h,mobile=1h/2160h" of access/refresh lifetimes.
+// Any caller can send any client name, so these are defaults to suit each
+// app, not a limit: the longest lifetime listed is the one to budget for.
+func newTokenLifetimes() (auth.Lifetimes, map[string]auth.Lifetimes, error) {
+	lifetimes := auth.Lifetimes{
+		Access:  time.Hour,
+		Refresh: 60 * 24 * time.Hour,
+	}
+	for env, lifetime := range map[string]*time.Duration{
+		"ACCESS_TOKEN_LIFETIME":  &lifetimes.Access,
+		"REFRESH_TOKEN_LIFETIME": &lifetimes.Refresh,
+	} {
+		if value := os.Getenv(env); value != "" {
+			var err error
+			*lifetime, err = time.ParseDuration(value)
+			if err != nil || *lifetime <= 0 {
+				return auth.Lifetimes{}, nil, fmt.Errorf("invalid %s %q", env, value)
+			}
+		}
+	}
+
+	clients, err := auth.ParseClientLifetimes(os.Getenv("CLIENT_TOKEN_LIFETIMES"))
+	if err != nil {
+		return auth.Lifetimes{}, nil, fmt.Errorf("invalid CLIENT_TOKEN_LIFETIMES: %w", err)
+	}
+
+	return lifetimes, clients, nil
+}
+
+// client returns the name r's client gave in the Chirpy-Client header, or
+// "" if it didn't give one that has lifetimes of its own. The header is
+// whatever the caller says it is, so nothing should rely on it for
+// security.
+func (cfg *apiConfig) client(r *http.Request) string {
+	client := strings.ToLower(r.Header.Get(clientHeader))
+	if _, ok := cfg.clientLifetimes[client]; !ok {
+		return ""
+	}
+	return client
+}
+
+func (cfg *apiConfig) lifetimesFor(client string) auth.Lifetimes {
+	if lifetimes, ok := cfg.clientLifetimes[client]; ok {
+		return lifetimes
+	}
+	return cfg.lifetimes
+}
+
+// loginResponse signs the user in, issuing an access token and a refresh
+// token that starts a session on the device r came from. Every way of
+// logging in answers with this same payload.
+func (cfg *apiConfig) loginResponse(r *http.Request, user database.User) (map[string]any, error) {
+	client := cfg.client(r)
+	lifetimes := cfg.lifetimesFor(client)
+	accessToken, err := auth.MakeJWT(user.ID, cfg.keys, lifetimes.Access)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := auth.MakeRefreshToken()
+
+	_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(lifetimes.Refresh),
+		UserAgent: userAgent(r),
+		IpAddress: cfg.clientIP(r),
+		Client:    client,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"id":                       user.ID,
+		"created_at":               user.CreatedAt,
+		"updated_at":               user.UpdatedAt,
+		"email":                    user.Email,
+		"token":                    accessToken,
+		"expires_in":               int(lifetimes.Access.Seconds()),
+		"refresh_token":            refreshToken,
+		"refresh_token_expires_in": int(lifetimes.Refresh.Seconds()),
+		"is_chirpy_red":            user.IsChirpyRed,
+		"email_verified":           user.EmailVerifiedAt.Valid,
+		"role":                     user.Role,
+	}, nil
+}
+
+// handlerMagicLinkRequest emails a one-time sign-in link. The link only
+// works together with the device code handed back here, which is also set
+// as a cookie, so a link forwarded to or intercepted by someone else is
+// useless to them.
+func (cfg *apiConfig) handlerMagicLinkRequest(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	// Unknown addresses get a device code too, so the response doesn't
+	// tell whether an account exists.
+	deviceCode := auth.MakeRefreshToken()
+	go func(email string) {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := cfg.sendMagicLink(ctx, email, deviceCode); err != nil {
+			log.Printf("sending magic link: %s", err)
+		}
+	}(params.Email)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicDeviceCookie,
+		Value:    deviceCode,
+		Path:     "/api/login/magic",
+		MaxAge:   int(magicLinkLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	respondWithJSON(w, http.StatusAccepted, map[string]any{
+		"device_code": deviceCode,
+		"expires_in":  int(magicLinkLifetime.Seconds()),
+	})
+}
+
+func (cfg *apiConfig) sendMagicLink(ctx context.Context, email, deviceCode string) error {
+	user, err := cfg.db.GetUser(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token := auth.MakeRefreshToken()
+	err = cfg.db.CreateMagicLink(ctx, database.CreateMagicLinkParams{
+		TokenHash:  auth.HashToken(token),
+		UserID:     user.ID,
+		DeviceHash: auth.HashToken(deviceCode),
+		ExpiresAt:  time.Now().UTC().Add(magicLinkLifetime),
+	})
+	if err != nil {
+		return err
+	}
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy sign-in link",
+		Body: "Open this link on the device you asked from to sign in to Chirpy:\n" +
+			cfg.emailLink("/api/login/magic", token) + "\n\n" +
+			"The link works once and expires in 15 minutes. If you didn't ask for it, you can ignore this email.",
+	})
+}
+
+// handlerMagicLinkExchange trades a magic link for a login. Browsers follow
+// the link with GET and prove the device with the cookie; other clients
+// POST the token from the link together with their device code.
+func (cfg *apiConfig) handlerMagicLinkExchange(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token      string `json:"token"`
+		DeviceCode string `json:"device_code"`
+	}
+	params := parameters{}
+	if r.Method == http.MethodPost {
+		decoder := json.NewDecoder(r.Body)
+		if err := decoder.Decode(&params); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+			return
+		}
+	} else {
+		params.Token = r.URL.Query().Get("token")
+		if cookie, err := r.Cookie(magicDeviceCookie); err == nil {
+			params.DeviceCode = cookie.Value
+		}
+	}
+	if params.Token == "" || params.DeviceCode == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing token or device code")
+		return
+	}
+
+	userID, err := cfg.db.ConsumeMagicLink(r.Context(), database.ConsumeMagicLinkParams{
+		TokenHash:  auth.HashToken(params.Token),
+		DeviceHash: auth.HashToken(params.DeviceCode),
+		Now:        time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired sign-in link")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+	// Getting the link proves control of the address just like a
+	// verification email does.
+	if !user.EmailVerifiedAt.Valid {
+		if _, err := cfg.db.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+			ID:    user.ID,
+			Email: user.Email,
+		}); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		user.EmailVerifiedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	response, err := cfg.completeLogin(r, user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   magicDeviceCookie,
+		Path:   "/api/login/magic",
+		MaxAge: -1,
+	})
+	respondWithJSON(w, http.StatusOK, response)
+}
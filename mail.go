@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/dipzza/bootdev_chirpy/internal/mailer"
+)
+
+const (
+	emailTokenVerify          = "verify"
+	verificationTokenLifetime = 48 * time.Hour
+)
+
+// newMailer picks the mail sender from MAILER: "smtp" relays through
+// SMTP_ADDR, "file" writes .eml files to MAIL_DIR and anything else logs
+// the emails, which is the default for development.
+func newMailer() mailer.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "chirpy@localhost"
+	}
+
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return mailer.SMTPMailer{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			From:     from,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return mailer.FileMailer{Dir: dir, From: from}
+	}
+	return mailer.LogMailer{}
+}
+
+// emailLink builds an absolute link to path with a token query parameter.
+func (cfg *apiConfig) emailLink(path, token string) string {
+	return cfg.baseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeEmailToken(user.ID, user.Email, emailTokenVerify, cfg.emailTokenSecret, verificationTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: "Welcome to Chirpy!\n\n" +
+			"Open this link to verify your email address:\n" +
+			cfg.emailLink("/api/users/verify", token) + "\n\n" +
+			"The link expires in 48 hours. If you didn't sign up, you can ignore this email.",
+	})
+}
+
+func (cfg *apiConfig) handlerUserVerify(w http.ResponseWriter, r *http.Request) {
+	userID, email, err := auth.ValidateEmailToken(r.URL.Query().Get("token"), emailTokenVerify, cfg.emailTokenSecret)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil || user.Email != email {
+		respondWithError(w, http.StatusBadRequest, "This link is for an email address that is no longer on the account")
+		return
+	}
+	if _, err := cfg.db.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:    user.ID,
+		Email: email,
+	}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"email":          email,
+		"email_verified": true,
+	})
+}
+
+func (cfg *apiConfig) handlerVerificationResend(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email address is already verified")
+		return
+	}
+
+	if err := cfg.sendVerificationEmail(r.Context(), user); err != nil {
+		respondWithError(w, http.StatusBadGateway, "Couldn't send the verification email: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -12,6 +15,11 @@ import (
 
 	"github.com/dipzza/bootdev_chirpy/internal/auth"
 	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/dipzza/bootdev_chirpy/internal/mailer"
+	"github.com/dipzza/bootdev_chirpy/internal/media"
+	"github.com/dipzza/bootdev_chirpy/internal/moderation"
+	"github.com/dipzza/bootdev_chirpy/internal/oidc"
+	"github.com/dipzza/bootdev_chirpy/internal/webauthn"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -19,10 +27,23 @@ import (
 
 type apiConfig struct {
 	db *database.Queries
+	dbConn *sql.DB
 	platform string
 	secret string
+	emailTokenSecret string
+	keys *auth.Keyring
+	lifetimes auth.Lifetimes
+	clientLifetimes map[string]auth.Lifetimes
 	polka_key string
 	port string
+	moderator *moderation.Pipeline
+	media media.Storage
+	mailer mailer.Mailer
+	baseURL string
+	requireVerifiedEmail bool
+	trustProxyHeaders bool
+	webauthn webauthn.RelyingParty
+	oidcProviders map[string]*oidc.Provider
 }
 
 func main() {
@@ -36,13 +57,47 @@ func main() {
 	}
 
 	dbQueries := database.New(db)
+
+	moderator, err := newModerator(context.Background(), dbQueries)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	apiCfg := apiConfig{
 		db: dbQueries,
+		dbConn: db,
 		platform: os.Getenv("PLATFORM"),
 		secret: os.Getenv("SECRET"),
+		emailTokenSecret: os.Getenv("EMAIL_TOKEN_SECRET"),
 		polka_key: os.Getenv("POLKA_KEY"),
 		port: os.Getenv("PORT"),
+		moderator: moderator,
+		media: newMediaStorage(),
+		mailer: newMailer(),
+		baseURL: os.Getenv("BASE_URL"),
+		requireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+		trustProxyHeaders: os.Getenv("TRUST_PROXY_HEADERS") == "true",
+	}
+	if apiCfg.emailTokenSecret == "" {
+		log.Fatal("EMAIL_TOKEN_SECRET must be set")
+	}
+	if apiCfg.baseURL == "" {
+		apiCfg.baseURL = "http://localhost:" + apiCfg.port
+	}
+	apiCfg.webauthn = newRelyingParty(apiCfg.baseURL)
+	apiCfg.keys, err = newKeyring(apiCfg.secret)
+	if err != nil {
+		log.Fatal(err)
 	}
+	apiCfg.lifetimes, apiCfg.clientLifetimes, err = newTokenLifetimes()
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.oidcProviders, err = newOIDCProviders(apiCfg.baseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.bootstrapAdmins(context.Background())
 
 	apiMetrics := apiMetrics{}
 	serverMux := http.NewServeMux()
@@ -51,8 +106,9 @@ func main() {
 		w.WriteHeader(200)
 	}))
 
-	serverMux.Handle("GET /admin/metrics", apiMetrics.metrics())
-	serverMux.Handle("POST /admin/reset", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	serverMux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handlerJWKS)
+	serverMux.Handle("GET /admin/metrics", apiCfg.requireRole(roleAdmin, apiMetrics.metrics()))
+	serverMux.Handle("POST /admin/reset", apiCfg.requireRole(roleAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if apiCfg.platform != "dev" {
 			w.WriteHeader(http.StatusForbidden)
 			return
@@ -60,10 +116,18 @@ func main() {
 		
 		apiCfg.db.DeleteAllUsers(r.Context())
 		apiMetrics.reset().ServeHTTP(w, r)
-	}))
-	serverMux.Handle("/app/", http.StripPrefix("/app", 
+	})))
+	serverMux.Handle("POST /admin/moderation/reload", apiCfg.requireRole(roleModerator, http.HandlerFunc(apiCfg.handlerModerationReload)))
+	serverMux.Handle("GET /admin/chirps/held", apiCfg.requireRole(roleModerator, http.HandlerFunc(apiCfg.handlerHeldChirpsList)))
+	serverMux.Handle("POST /admin/chirps/{id}/approve", apiCfg.requireRole(roleModerator, http.HandlerFunc(apiCfg.handlerHeldChirpApprove)))
+	serverMux.Handle("DELETE /admin/chirps/{id}", apiCfg.requireRole(roleModerator, http.HandlerFunc(apiCfg.handlerHeldChirpReject)))
+	serverMux.Handle("PUT /admin/users/{id}/role", apiCfg.requireRole(roleAdmin, http.HandlerFunc(apiCfg.handlerUserRoleUpdate)))
+	serverMux.Handle("GET /admin/role-changes", apiCfg.requireRole(roleAdmin, http.HandlerFunc(apiCfg.handlerRoleChangesList)))
+	serverMux.Handle("POST /admin/users/{id}/unlock", apiCfg.requireRole(roleAdmin, http.HandlerFunc(apiCfg.handlerUserUnlock)))
+	serverMux.Handle("DELETE /admin/login-blocks/{ip}", apiCfg.requireRole(roleAdmin, http.HandlerFunc(apiCfg.handlerLoginIPUnlock)))
+	serverMux.Handle("/app/", noFraming(http.StripPrefix("/app", 
 			apiMetrics.middlewareCountServerHit(http.FileServer(http.Dir("."))),
-		),
+		)),
 	)
 
 	serverMux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
@@ -84,57 +148,46 @@ func main() {
 			return
 		}
 		
-		user, err := apiCfg.db.GetUser(r.Context(), params.Email)
-		authorized := err == nil && auth.CheckPasswordHash(params.Password, user.HashedPassword)
-		if !authorized {
-			respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
-			return
-		}
-
-		accessToken, err := auth.MakeJWT(user.ID, apiCfg.secret)
+		user, err := apiCfg.checkLogin(r.Context(), apiCfg.clientIP(r), params.Email, params.Password)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondLoginError(w, err)
 			return
 		}
 
-		refreshToken := auth.MakeRefreshToken()
-
-		_, err = apiCfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-			Token: refreshToken,
-			UserID: user.ID,
-			ExpiresAt: time.Now().UTC().Add(time.Hour * 24 * 60),
-		})
+		responseBody, err := apiCfg.completeLogin(r, user)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		responseBody := map[string]any{
-			"id": user.ID,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
-			"email": user.Email,
-			"token": accessToken,
-			"refresh_token": refreshToken,
-			"is_chirpy_red": user.IsChirpyRed,
-		}
-
 		respondWithJSON(w, http.StatusOK, responseBody)
 	})
 	serverMux.HandleFunc("POST /api/refresh", func(w http.ResponseWriter, r *http.Request) {
 		token, err := auth.GetBearerToken(r.Header)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, err.Error())
+			respondUnauthorized(w, err)
 			return
 		}
 
-		user_id, err := apiCfg.db.GetUserFromRefreshToken(r.Context(), token)
+		refreshToken, err := apiCfg.db.GetUserFromRefreshToken(r.Context(), database.GetUserFromRefreshTokenParams{
+			Token: token,
+			Now: time.Now().UTC(),
+		})
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, err.Error())
+			respondUnauthorized(w, err)
+			return
+		}
+		err = apiCfg.db.TouchRefreshToken(r.Context(), database.TouchRefreshTokenParams{
+			Token: token,
+			IpAddress: apiCfg.clientIP(r),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		jwt, err := auth.MakeJWT(user_id, apiCfg.secret)
+		lifetimes := apiCfg.lifetimesFor(refreshToken.Client)
+		jwt, err := auth.MakeJWT(refreshToken.UserID, apiCfg.keys, lifetimes.Access)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -142,12 +195,13 @@ func main() {
 
 		respondWithJSON(w, http.StatusOK, map[string]any{
 			"token": jwt,
+			"expires_in": int(lifetimes.Access.Seconds()),
 		})
 	})
 	serverMux.HandleFunc("POST /api/revoke", func(w http.ResponseWriter, r *http.Request) {
 		token, err := auth.GetBearerToken(r.Header)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, err.Error())
+			respondUnauthorized(w, err)
 			return
 		}
 
@@ -184,6 +238,9 @@ func main() {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		if err := apiCfg.sendVerificationEmail(r.Context(), user); err != nil {
+			log.Printf("sending verification email to user %s: %s", user.ID, err)
+		}
 		respondWithJSON(w, http.StatusCreated, user)
 	})
 	serverMux.HandleFunc("POST /api/polka/webhooks", func(w http.ResponseWriter, r *http.Request) {
@@ -231,8 +288,94 @@ func main() {
 		respondWithJSON(w, http.StatusNoContent, nil)
 	})
 
-	serverMux.HandleFunc("GET /api/chirps", func(w http.ResponseWriter, r *http.Request) {
+	serverMux.Handle("POST /api/media", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerMediaUpload)))
+	serverMux.Handle("GET /api/media/{id}", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerMediaGet)))
+	serverMux.Handle("GET /api/media/{id}/thumbnail", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerMediaThumbnailGet)))
+	serverMux.Handle("POST /api/chirps/{id}/poll/vote", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerPollVote)))
+	serverMux.Handle("GET /api/chirps/scheduled", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerScheduledChirpsList)))
+	serverMux.Handle("DELETE /api/chirps/{id}", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerChirpDelete)))
+	serverMux.Handle("POST /api/chirps/{id}/pin", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerChirpPin)))
+	serverMux.Handle("DELETE /api/chirps/{id}/pin", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerChirpUnpin)))
+	serverMux.Handle("GET /api/users/{id}", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerUserProfile)))
+	serverMux.Handle("POST /api/users/{id}/follow", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerUserFollow)))
+	serverMux.Handle("DELETE /api/users/{id}/follow", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerUserUnfollow)))
+	serverMux.HandleFunc("POST /api/login/mfa", apiCfg.handlerLoginMFA)
+	serverMux.HandleFunc("POST /api/users/me/totp", apiCfg.handlerTOTPEnroll)
+	serverMux.HandleFunc("GET /api/users/me/totp/qr", apiCfg.handlerTOTPQRCode)
+	serverMux.HandleFunc("POST /api/users/me/totp/confirm", apiCfg.handlerTOTPConfirm)
+	serverMux.HandleFunc("DELETE /api/users/me/totp", apiCfg.handlerTOTPDisable)
+	serverMux.HandleFunc("GET /api/sessions", apiCfg.handlerSessionsList)
+	serverMux.HandleFunc("DELETE /api/sessions/{id}", apiCfg.handlerSessionRevoke)
+	serverMux.HandleFunc("DELETE /api/sessions", apiCfg.handlerSessionsRevokeAll)
+	serverMux.HandleFunc("POST /api/tokens", apiCfg.handlerAPITokenCreate)
+	serverMux.HandleFunc("GET /api/tokens", apiCfg.handlerAPITokensList)
+	serverMux.HandleFunc("DELETE /api/tokens/{id}", apiCfg.handlerAPITokenRevoke)
+	serverMux.HandleFunc("POST /api/oauth/clients", apiCfg.handlerOAuthClientCreate)
+	serverMux.HandleFunc("GET /api/oauth/clients", apiCfg.handlerOAuthClientsList)
+	serverMux.HandleFunc("DELETE /api/oauth/clients/{id}", apiCfg.handlerOAuthClientDelete)
+	serverMux.HandleFunc("GET /api/oauth/authorize", apiCfg.handlerOAuthAuthorizeInfo)
+	serverMux.HandleFunc("POST /api/oauth/authorize", apiCfg.handlerOAuthAuthorize)
+	serverMux.HandleFunc("POST /api/oauth/token", apiCfg.handlerOAuthToken)
+	serverMux.HandleFunc("POST /api/oauth/revoke", apiCfg.handlerOAuthRevoke)
+	serverMux.HandleFunc("POST /api/login/passkey/begin", apiCfg.handlerPasskeyLoginBegin)
+	serverMux.HandleFunc("POST /api/login/passkey/finish", apiCfg.handlerPasskeyLoginFinish)
+	serverMux.HandleFunc("POST /api/passkeys/register/begin", apiCfg.handlerPasskeyRegisterBegin)
+	serverMux.HandleFunc("POST /api/passkeys/register/finish", apiCfg.handlerPasskeyRegisterFinish)
+	serverMux.HandleFunc("GET /api/passkeys", apiCfg.handlerPasskeysList)
+	serverMux.HandleFunc("PUT /api/passkeys/{id}", apiCfg.handlerPasskeyRename)
+	serverMux.HandleFunc("DELETE /api/passkeys/{id}", apiCfg.handlerPasskeyDelete)
+	serverMux.HandleFunc("POST /api/login/magic", apiCfg.handlerMagicLinkRequest)
+	serverMux.HandleFunc("GET /api/login/magic", apiCfg.handlerMagicLinkExchange)
+	serverMux.HandleFunc("POST /api/login/magic/exchange", apiCfg.handlerMagicLinkExchange)
+	serverMux.HandleFunc("GET /api/login/oidc/{provider}", apiCfg.handlerOIDCLoginBegin)
+	serverMux.HandleFunc("GET /api/login/oidc/{provider}/callback", apiCfg.handlerOIDCLoginCallback)
+	serverMux.HandleFunc("GET /api/users/me/identities", apiCfg.handlerExternalIdentitiesList)
+	serverMux.HandleFunc("DELETE /api/users/me/identities/{id}", apiCfg.handlerExternalIdentityUnlink)
+	serverMux.HandleFunc("POST /api/password/forgot", apiCfg.handlerPasswordForgot)
+	serverMux.HandleFunc("POST /api/password/reset", apiCfg.handlerPasswordReset)
+	serverMux.HandleFunc("GET /api/users/verify", apiCfg.handlerUserVerify)
+	serverMux.HandleFunc("POST /api/users/verify/resend", apiCfg.handlerVerificationResend)
+	serverMux.HandleFunc("DELETE /api/users/me", apiCfg.handlerAccountDelete)
+	serverMux.HandleFunc("POST /api/users/me/restore", apiCfg.handlerAccountRestore)
+	serverMux.HandleFunc("GET /api/users/me/export", apiCfg.handlerAccountExport)
+	serverMux.Handle("PUT /api/users/me/protected", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerUserProtectedUpdate)))
+	serverMux.Handle("GET /api/users/me/follow_requests", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerFollowRequestsList)))
+	serverMux.Handle("POST /api/users/me/follow_requests/{id}/approve", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerFollowRequestApprove)))
+	serverMux.Handle("DELETE /api/users/me/follow_requests/{id}", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerFollowRequestDeny)))
+
+	serverMux.Handle("GET /api/users/me/bookmarks", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerBookmarksList)))
+	serverMux.Handle("POST /api/users/me/bookmarks", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerBookmarkCreate)))
+	serverMux.Handle("DELETE /api/users/me/bookmarks/{chirp_id}", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerBookmarkDelete)))
+	serverMux.Handle("GET /api/users/me/bookmarks/collections", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerBookmarkCollectionsList)))
+	serverMux.Handle("POST /api/users/me/bookmarks/collections", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerBookmarkCollectionCreate)))
+	serverMux.Handle("PUT /api/users/me/bookmarks/collections/{id}", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerBookmarkCollectionRename)))
+	serverMux.Handle("DELETE /api/users/me/bookmarks/collections/{id}", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerBookmarkCollectionDelete)))
+
+	serverMux.Handle("GET /api/timeline", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerHomeTimeline)))
+	serverMux.Handle("GET /api/lists", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerListsList)))
+	serverMux.Handle("POST /api/lists", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerListCreate)))
+	serverMux.Handle("GET /api/lists/{id}", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerListGet)))
+	serverMux.Handle("PUT /api/lists/{id}", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerListUpdate)))
+	serverMux.Handle("DELETE /api/lists/{id}", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerListDelete)))
+	serverMux.Handle("POST /api/lists/{id}/members", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerListMemberAdd)))
+	serverMux.Handle("DELETE /api/lists/{id}/members/{user_id}", apiCfg.acceptAPITokens(scopeProfileWrite, http.HandlerFunc(apiCfg.handlerListMemberRemove)))
+	serverMux.Handle("GET /api/lists/{id}/timeline", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(apiCfg.handlerListTimeline)))
+
+	serverMux.Handle("POST /api/drafts", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerDraftCreate)))
+	serverMux.Handle("GET /api/drafts", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerDraftsList)))
+	serverMux.Handle("GET /api/drafts/{id}", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerDraftGet)))
+	serverMux.Handle("PUT /api/drafts/{id}", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerDraftUpdate)))
+	serverMux.Handle("DELETE /api/drafts/{id}", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(apiCfg.handlerDraftDelete)))
+
+	serverMux.Handle("GET /api/chirps", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		viewer, err := apiCfg.optionalUser(r)
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+
 		var chirps []database.Chirp
+		var authorUUID uuid.UUID
 		author_id := r.URL.Query().Get("author_id")
 		if author_id != "" {
 			userUUID, err := uuid.Parse(author_id)
@@ -240,6 +383,7 @@ func main() {
 				respondWithError(w, http.StatusBadRequest, "Invalid UUID:" + err.Error())
 				return
 			}
+			authorUUID = userUUID
 			
 			chirps, err = apiCfg.db.GetChirpsByAuthor(r.Context(), userUUID)
 			if err != nil {
@@ -254,20 +398,56 @@ func main() {
 			}
 		}
 
+		chirps, err = apiCfg.visibleChirps(r.Context(), chirps, viewer)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
 		sortOption := r.URL.Query().Get("sort")
 		if sortOption == "desc" {
 			slices.SortFunc(chirps, func(a, b database.Chirp) int {
-				return b.CreatedAt.Compare(a.CreatedAt)
+				return b.PublishAt.Compare(a.PublishAt)
 			})
 		} else {
 			slices.SortFunc(chirps, func(a, b database.Chirp) int {
-				return a.CreatedAt.Compare(b.CreatedAt)
+				return a.PublishAt.Compare(b.PublishAt)
 			})
 		}
 
-		respondWithJSON(w, http.StatusOK, chirps)
-	})
-	serverMux.HandleFunc("GET /api/chirps/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var pinned []database.Chirp
+		if author_id != "" && r.URL.Query().Get("include_pinned") == "true" {
+			pinned, err = apiCfg.pinnedChirps(r.Context(), authorUUID, viewer)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			chirps = slices.DeleteFunc(chirps, func(chirp database.Chirp) bool {
+				return slices.ContainsFunc(pinned, func(p database.Chirp) bool {
+					return p.ID == chirp.ID
+				})
+			})
+			chirps = append(pinned, chirps...)
+		}
+
+		response, err := apiCfg.chirpsResponse(r.Context(), chirps, viewer)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for i := range pinned {
+			response[i].Pinned = true
+		}
+
+		respondWithJSON(w, http.StatusOK, response)
+	})))
+	serverMux.Handle("GET /api/chirps/{id}", apiCfg.acceptAPITokens(scopeChirpsRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		viewer, err := apiCfg.optionalUser(r)
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+
 		userUUID, err := uuid.Parse(r.PathValue("id"))
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid UUID:" + err.Error())
@@ -279,23 +459,49 @@ func main() {
 			respondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		visible, err := apiCfg.chirpVisibleTo(r.Context(), chirp, viewer)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !visible {
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+			return
+		}
 
-		respondWithJSON(w, http.StatusOK, chirp)
-	})
-	serverMux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
-		token, err := auth.GetBearerToken(r.Header)
+		response, err := apiCfg.chirpResponse(r.Context(), chirp, viewer)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, err.Error())
+			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
-		userID, err := auth.ValidateJWT(token, apiCfg.secret)
+
+		respondWithJSON(w, http.StatusOK, response)
+	})))
+	serverMux.Handle("POST /api/chirps", apiCfg.acceptAPITokens(scopeChirpsWrite, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := apiCfg.authenticatedUser(r)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, err.Error())
+			respondUnauthorized(w, err)
 			return
 		}
+		if apiCfg.requireVerifiedEmail {
+			user, err := apiCfg.db.GetUserByID(r.Context(), userID)
+			if err != nil {
+				respondWithError(w, http.StatusUnauthorized, "User not found")
+				return
+			}
+			if !user.EmailVerifiedAt.Valid {
+				respondWithError(w, http.StatusForbidden, "Verify your email address before chirping")
+				return
+			}
+		}
 		
 		type parameters struct {
 			Body string `json:"body"`
+			MediaIDs []uuid.UUID `json:"media_ids"`
+			Poll *pollParameters `json:"poll"`
+			PublishAt *time.Time `json:"publish_at"`
+			Visibility string `json:"visibility"`
+			Mentions []uuid.UUID `json:"mentions"`
 		}
 
 		decoder := json.NewDecoder(r.Body)
@@ -309,18 +515,134 @@ func main() {
 			respondWithError(w, http.StatusBadRequest, "Chirp is too long")
 			return
 		}
-		cleanedBody := replaceBadWords(params.Body)
+		if len(params.MediaIDs) > maxChirpMedia {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("A chirp can't have more than %d media attachments", maxChirpMedia))
+			return
+		}
+		if params.Visibility == "" {
+			params.Visibility = visibilityPublic
+		}
+		if !validVisibility(params.Visibility) {
+			respondWithError(w, http.StatusBadRequest, "Visibility must be one of public, followers or mentioned")
+			return
+		}
+		if len(params.Mentions) > maxChirpMentions {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("A chirp can't mention more than %d users", maxChirpMentions))
+			return
+		}
+
+		publishAt := time.Now().UTC()
+		if params.PublishAt != nil {
+			if !params.PublishAt.After(publishAt) {
+				respondWithError(w, http.StatusBadRequest, "publish_at must be in the future")
+				return
+			}
+			publishAt = params.PublishAt.UTC()
+		}
+		if params.Poll != nil {
+			if err := params.Poll.validate(publishAt); err != nil {
+				respondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
 
-		chirp, err := apiCfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-			Body: cleanedBody,
+		decision, err := apiCfg.moderator.Run(r.Context(), params.Body)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if params.Poll != nil && !decision.Rejected {
+			pollDecision, err := params.Poll.moderate(r.Context(), apiCfg.moderator)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			decision.Rejected = pollDecision.Rejected
+			decision.Flagged = decision.Flagged || pollDecision.Flagged
+			decision.Held = decision.Held || pollDecision.Held
+			decision.Reasons = append(decision.Reasons, pollDecision.Reasons...)
+		}
+		if decision.Rejected {
+			respondWithError(w, http.StatusBadRequest, "Chirp rejected by moderation: " + strings.Join(decision.Reasons, "; "))
+			return
+		}
+
+		tx, err := apiCfg.dbConn.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer tx.Rollback()
+		qtx := apiCfg.db.WithTx(tx)
+
+		chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+			Body: decision.Body,
 			UserID: userID,
+			Flagged: decision.Flagged,
+			Held: decision.Held,
+			PublishAt: publishAt,
+			Published: params.PublishAt == nil,
+			Visibility: params.Visibility,
 		})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
-		respondWithJSON(w, http.StatusCreated, chirp)
-	})
+		for i, mediaID := range params.MediaIDs {
+			attached, err := qtx.AttachMediaFile(r.Context(), database.AttachMediaFileParams{
+				ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+				Position: int32(i),
+				ID: mediaID,
+				UserID: userID,
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if attached == 0 {
+				respondWithError(w, http.StatusBadRequest, "Media " + mediaID.String() + " doesn't exist or is already attached")
+				return
+			}
+		}
+		for _, mentionedID := range params.Mentions {
+			err := qtx.CreateChirpMention(r.Context(), database.CreateChirpMentionParams{
+				ChirpID: chirp.ID,
+				UserID: mentionedID,
+			})
+			if isForeignKeyViolation(err) {
+				respondWithError(w, http.StatusBadRequest, "Mentioned user " + mentionedID.String() + " doesn't exist")
+				return
+			}
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		if params.Poll != nil {
+			if err := createPoll(r.Context(), qtx, chirp.ID, *params.Poll); err != nil {
+				respondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response, err := apiCfg.chirpResponse(r.Context(), chirp, userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if chirp.Held {
+			log.Printf("chirp %s held for review: %s", chirp.ID, strings.Join(decision.Reasons, "; "))
+			respondWithJSON(w, http.StatusAccepted, response)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, response)
+	})))
+
+	go apiCfg.runScheduler(context.Background(), schedulerInterval)
 
 	server := http.Server{
 		Addr:    ":" + apiCfg.port,
@@ -353,16 +675,58 @@ func respondWithError(w http.ResponseWriter, code int, msg string) {
 	respondWithJSON(w, code, jsonErr)
 }
 
-func replaceBadWords(s string) string {
-	badWords := map[string]bool{"kerfuffle": true, "sharbert": true, "fornax": true}
+// respondUnauthorized rejects a request whose access token was missing or
+// invalid. The WWW-Authenticate challenge follows RFC 6750, so clients can
+// tell an expired token, which refreshing fixes, from a bad one.
+func respondUnauthorized(w http.ResponseWriter, err error) {
+	challenge := `Bearer realm="chirpy"`
+	if errors.Is(err, auth.ErrInsufficientScope) {
+		w.Header().Set("WWW-Authenticate", challenge+`, error="insufficient_scope"`)
+		respondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	var tokenErr *auth.TokenError
+	if errors.As(err, &tokenErr) {
+		description := "The access token is invalid"
+		if errors.Is(err, auth.ErrTokenExpired) {
+			description = "The access token expired"
+		}
+		challenge += `, error="invalid_token", error_description="` + description + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	respondWithError(w, http.StatusUnauthorized, err.Error())
+}
 
-	words := strings.Split(s, " ")
-	for i, word := range words {
-		lowered := strings.ToLower(word)
-		if badWords[lowered] {
-			words[i] = "****"
+// authenticatedUser returns the user an access token was issued to, or
+// the owner of a personal access token accepted by acceptAPITokens.
+func (cfg *apiConfig) authenticatedUser(r *http.Request) (uuid.UUID, error) {
+	if userID, ok := r.Context().Value(apiTokenUserKey{}).(uuid.UUID); ok {
+		return userID, nil
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	userID, scopes, err := auth.ParseAccessToken(token, cfg.keys)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	// Tokens issued to OAuth clients only work on routes that accept their
+	// scopes through acceptAPITokens.
+	if scopes != nil {
+		scope, _ := r.Context().Value(routeScopeKey{}).(string)
+		if !slices.Contains(scopes, scope) {
+			return uuid.UUID{}, auth.ErrInsufficientScope
 		}
 	}
+	return userID, nil
+}
 
-	return strings.Join(words, " ")
+// optionalUser is authenticatedUser for endpoints that also serve anonymous
+// requests. It returns uuid.Nil when no Authorization header is sent.
+func (cfg *apiConfig) optionalUser(r *http.Request) (uuid.UUID, error) {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.Nil, nil
+	}
+	return cfg.authenticatedUser(r)
 }
\ No newline at end of file
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/dipzza/bootdev_chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const (
+	maxUploadSize = 5 << 20
+	maxChirpMedia = 4
+)
+
+// purgeUnattachedMedia deletes uploads that weren't attached to a chirp
+// within a day of being uploaded, along with their blobs.
+func (cfg *apiConfig) purgeUnattachedMedia(ctx context.Context) {
+	files, err := cfg.db.DeleteUnattachedMediaFiles(ctx)
+	if err != nil {
+		log.Printf("scheduler: deleting unattached media: %s", err)
+		return
+	}
+	for _, file := range files {
+		for _, key := range []string{file.StorageKey, file.ThumbnailKey} {
+			if err := cfg.media.Delete(ctx, key); err != nil {
+				log.Printf("deleting media %s: %s", key, err)
+			}
+		}
+	}
+}
+
+func newMediaStorage() media.Storage {
+	if os.Getenv("MEDIA_STORAGE") == "s3" {
+		return media.S3Storage{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+	}
+
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = "media"
+	}
+	return media.LocalStorage{Dir: dir}
+}
+
+type Attachment struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+func attachmentResponse(file database.MediaFile) Attachment {
+	return Attachment{
+		ID:           file.ID,
+		ContentType:  file.ContentType,
+		Width:        file.Width,
+		Height:       file.Height,
+		URL:          "/api/media/" + file.ID.String(),
+		ThumbnailURL: "/api/media/" + file.ID.String() + "/thumbnail",
+	}
+}
+
+func (cfg *apiConfig) handlerMediaUpload(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+1<<20)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid upload: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid upload: "+err.Error())
+		return
+	}
+	if len(data) > maxUploadSize {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Media can't be larger than %d bytes", maxUploadSize))
+		return
+	}
+
+	processed, err := media.ProcessImage(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported")
+		return
+	}
+	if errors.Is(err, media.ErrImageTooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Images can't be wider or taller than %d pixels, have more than %d pixels or have more than %d frames", media.MaxImageSide, media.MaxImagePixels, media.MaxGIFFrames))
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid image: "+err.Error())
+		return
+	}
+
+	mediaID := uuid.New()
+	storageKey := "media/" + mediaID.String()
+	thumbnailKey := storageKey + "_thumb"
+	if err := cfg.media.Put(r.Context(), storageKey, processed.Data, processed.ContentType); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := cfg.media.Put(r.Context(), thumbnailKey, processed.Thumbnail, media.ThumbnailContentType(processed.ContentType)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	mediaFile, err := cfg.db.CreateMediaFile(r.Context(), database.CreateMediaFileParams{
+		ID:           mediaID,
+		UserID:       userID,
+		ContentType:  processed.ContentType,
+		Size:         int32(len(processed.Data)),
+		Width:        int32(processed.Width),
+		Height:       int32(processed.Height),
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, attachmentResponse(mediaFile))
+}
+
+func (cfg *apiConfig) handlerMediaGet(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, false)
+}
+
+func (cfg *apiConfig) handlerMediaThumbnailGet(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, true)
+}
+
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	mediaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	mediaFile, err := cfg.db.GetMediaFile(r.Context(), mediaID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Media not found")
+		return
+	}
+	if mediaFile.ChirpID.Valid {
+		viewer, err := cfg.optionalUser(r)
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+		chirp, err := cfg.db.GetChirp(r.Context(), mediaFile.ChirpID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Media not found")
+			return
+		}
+		visible, err := cfg.chirpVisibleTo(r.Context(), chirp, viewer)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !visible {
+			respondWithError(w, http.StatusNotFound, "Media not found")
+			return
+		}
+	}
+
+	key, contentType := mediaFile.StorageKey, mediaFile.ContentType
+	if thumbnail {
+		key, contentType = mediaFile.ThumbnailKey, media.ThumbnailContentType(mediaFile.ContentType)
+	}
+
+	blob, err := cfg.media.Get(r.Context(), key)
+	if errors.Is(err, media.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Media not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, blob)
+}
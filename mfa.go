@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	totpIssuer           = "Chirpy"
+	recoveryCodeCount    = 10
+	mfaChallengeLifetime = 5 * time.Minute
+	maxMFAAttempts       = 5
+)
+
+// completeLogin finishes a first login factor. Users with two-factor
+// authentication get a short-lived challenge token to exchange at
+// /api/login/mfa instead of their tokens.
+func (cfg *apiConfig) completeLogin(r *http.Request, user database.User) (map[string]any, error) {
+	if !user.TotpEnabled {
+		return cfg.loginResponse(r, user)
+	}
+
+	token := auth.MakeRefreshToken()
+	err := cfg.db.CreateMFAChallenge(r.Context(), database.CreateMFAChallengeParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(mfaChallengeLifetime),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"mfa_required": true,
+		"mfa_token":    token,
+		"expires_in":   int(mfaChallengeLifetime.Seconds()),
+	}, nil
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused
+// recovery code. Both are single-use.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, user database.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)),
+		})
+		return used == 1, err
+	}
+
+	step, ok := auth.ValidateTOTP(user.TotpSecret.String, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	used, err := cfg.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		ID:           user.ID,
+		TotpLastStep: step,
+	})
+	return used == 1, err
+}
+
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	tokenHash := auth.HashToken(params.MFAToken)
+	userID, err := cfg.db.AttemptMFAChallenge(r.Context(), database.AttemptMFAChallengeParams{
+		TokenHash:   tokenHash,
+		Now:         time.Now().UTC(),
+		MaxAttempts: maxMFAAttempts,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token, log in again")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+	ok, err := cfg.checkSecondFactor(r.Context(), user, params.Code, params.RecoveryCode)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect code")
+		return
+	}
+
+	if err := cfg.db.DeleteMFAChallenge(r.Context(), tokenHash); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response, err := cfg.loginResponse(r, user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// handlerTOTPEnroll starts enrollment with a new secret. It only takes
+// effect once a code from it is confirmed, so starting over is harmless.
+func (cfg *apiConfig) handlerTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	secret := auth.GenerateTOTPSecret()
+	updated, err := cfg.db.SetPendingTOTPSecret(r.Context(), database.SetPendingTOTPSecretParams{
+		ID:         userID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, map[string]any{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPURI(secret, totpIssuer, user.Email),
+		"qr_code_url": "/api/users/me/totp/qr",
+	})
+}
+
+// handlerTOTPQRCode renders the pending secret as a QR code. It stops
+// working once enrollment is confirmed so the secret can't be read back.
+func (cfg *apiConfig) handlerTOTPQRCode(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.TotpEnabled || !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusNotFound, "No two-factor enrollment in progress")
+		return
+	}
+
+	png, err := qrcode.Encode(auth.TOTPURI(user.TotpSecret.String, totpIssuer, user.Email), qrcode.Medium, 256)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
+
+func (cfg *apiConfig) handlerTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.TotpEnabled {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusNotFound, "No two-factor enrollment in progress")
+		return
+	}
+	step, ok := auth.ValidateTOTP(user.TotpSecret.String, params.Code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Incorrect code")
+		return
+	}
+
+	codes, err := cfg.enableTOTP(r.Context(), userID, step)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"recovery_codes": codes,
+	})
+}
+
+// enableTOTP turns two-factor authentication on and returns a fresh set of
+// recovery codes. Only their hashes are kept, so this is the one time the
+// user gets to see them.
+func (cfg *apiConfig) enableTOTP(ctx context.Context, userID uuid.UUID, step int64) ([]string, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	enabled, err := qtx.EnableTOTP(ctx, database.EnableTOTPParams{
+		ID:           userID,
+		TotpLastStep: step,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if enabled == 0 {
+		return nil, errors.New("two-factor enrollment changed while confirming it")
+	}
+	if err := qtx.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	codes := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	for _, code := range codes {
+		err := qtx.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, tx.Commit()
+}
+
+func (cfg *apiConfig) handlerTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err := cfg.checkPassword(r.Context(), cfg.clientIP(r), user, params.Password); err != nil {
+		respondLoginError(w, err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if err := qtx.DisableTOTP(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
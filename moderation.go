@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/dipzza/bootdev_chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+// newModerator builds the moderation pipeline chirps go through before they
+// are stored. Stages run in the order they are appended here.
+func newModerator(ctx context.Context, db *database.Queries) (*moderation.Pipeline, error) {
+	wordsAction := moderation.ActionMask
+	action := os.Getenv("BANNED_WORDS_ACTION")
+	// MODERATION_MODE is what BANNED_WORDS_ACTION used to be called, and
+	// its values are still valid actions.
+	if mode := os.Getenv("MODERATION_MODE"); action == "" && mode != "" {
+		log.Printf("MODERATION_MODE is deprecated, set BANNED_WORDS_ACTION=%s instead", strings.ToLower(mode))
+		action = strings.ToLower(mode)
+	}
+	if action != "" {
+		var err error
+		if wordsAction, err = moderation.ParseAction(action); err != nil {
+			return nil, err
+		}
+	}
+	var wordSource moderation.Source = moderation.SourceFunc(db.GetBannedWords)
+	if path := os.Getenv("BANNED_WORDS_FILE"); path != "" {
+		wordSource = moderation.FileSource{Path: path}
+	}
+	stages := []moderation.Stage{moderation.NewFilter(wordSource, wordsAction)}
+
+	if path := os.Getenv("BLOCKED_DOMAINS_FILE"); path != "" {
+		stages = append(stages, moderation.NewLinkBlocklist(moderation.FileSource{Path: path}, moderation.ActionReject))
+	}
+
+	stages = append(stages, moderation.DefaultSpamHeuristics())
+
+	if url := os.Getenv("CLASSIFIER_URL"); url != "" {
+		stages = append(stages, moderation.NewHTTPClassifier(url))
+	}
+
+	moderator := moderation.NewPipeline(stages...)
+	if _, err := moderator.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	return moderator, nil
+}
+
+func (cfg *apiConfig) handlerModerationReload(w http.ResponseWriter, r *http.Request) {
+	counts, err := cfg.moderator.Reload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, counts)
+}
+
+func (cfg *apiConfig) handlerHeldChirpsList(w http.ResponseWriter, r *http.Request) {
+	chirps, err := cfg.db.GetHeldChirps(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
+func (cfg *apiConfig) handlerHeldChirpApprove(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	chirp, err := cfg.db.ApproveChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "No held chirp with that ID")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+func (cfg *apiConfig) handlerHeldChirpReject(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || !chirp.Held {
+		respondWithError(w, http.StatusNotFound, "No held chirp with that ID")
+		return
+	}
+
+	if err := cfg.deleteChirp(r.Context(), chirpID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	oauthCodeLifetime       = 5 * time.Minute
+	maxOAuthClientNameLen   = 50
+	maxOAuthRedirectURIs    = 5
+	oauthClientSecretPrefix = "chirpy_cs_"
+)
+
+// OAuthClient is a third-party app registered to sign users in through
+// Chirpy's OAuth 2.0 authorization server.
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	// Secret is only set when a confidential client is registered; it can't
+	// be shown again.
+	Secret string `json:"client_secret,omitempty"`
+}
+
+func oauthClientResponse(client database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           client.ID,
+		CreatedAt:    client.CreatedAt,
+		Name:         client.Name,
+		RedirectURIs: strings.Fields(client.RedirectUris),
+		Confidential: client.SecretHash.Valid,
+	}
+}
+
+// OAuthGrant is a third-party app's access to a user's account, backed
+// by an OAuth refresh token.
+type OAuthGrant struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ClientID   uuid.UUID `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+}
+
+// oauthGrants lists the apps a user has authorized that can still get
+// new access tokens.
+func (cfg *apiConfig) oauthGrants(ctx context.Context, userID uuid.UUID) ([]OAuthGrant, error) {
+	tokens, err := cfg.db.GetOAuthGrantsByUser(ctx, database.GetOAuthGrantsByUserParams{
+		UserID: userID,
+		Now:    time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []OAuthGrant
+	clientNames := map[uuid.UUID]string{}
+	for _, token := range tokens {
+		clientID := token.OauthClientID.UUID
+		name, ok := clientNames[clientID]
+		if !ok {
+			client, err := cfg.db.GetOAuthClient(ctx, clientID)
+			if err != nil {
+				return nil, err
+			}
+			name = client.Name
+			clientNames[clientID] = name
+		}
+		grants = append(grants, OAuthGrant{
+			ID:         token.ID,
+			CreatedAt:  token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+			ClientID:   clientID,
+			ClientName: name,
+			Scopes:     strings.Fields(token.Scopes),
+		})
+	}
+	return grants, nil
+}
+
+// oauthError is an error response as defined by RFC 6749.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func respondWithOAuthError(w http.ResponseWriter, code int, err *oauthError) {
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, err)
+}
+
+// noFraming stops pages from being embedded in other sites, so the consent
+// screen can't be overlaid to trick users into approving a client.
+func noFraming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRedirectURI accepts https URIs, plain http only on the loopback
+// interface, and private-use schemes for native apps (RFC 8252).
+func validRedirectURI(raw string) error {
+	uri, err := url.Parse(raw)
+	if err != nil || uri.Scheme == "" || uri.Fragment != "" {
+		return fmt.Errorf("Redirect URI %q must be an absolute URI without a fragment", raw)
+	}
+	switch uri.Scheme {
+	case "https":
+		if uri.Host == "" {
+			return fmt.Errorf("Redirect URI %q has no host", raw)
+		}
+	case "http":
+		if host := uri.Hostname(); host != "localhost" && host != "127.0.0.1" && host != "::1" {
+			return fmt.Errorf("Redirect URI %q must use https", raw)
+		}
+	case "javascript", "data", "file", "vbscript":
+		return fmt.Errorf("Redirect URI %q has a forbidden scheme", raw)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerOAuthClientCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > maxOAuthClientNameLen {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Client names must be between 1 and %d characters", maxOAuthClientNameLen))
+		return
+	}
+	if len(params.RedirectURIs) == 0 || len(params.RedirectURIs) > maxOAuthRedirectURIs {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("A client needs between 1 and %d redirect URIs", maxOAuthRedirectURIs))
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		if strings.ContainsAny(uri, " \t\n") {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Redirect URI %q can't contain whitespace", uri))
+			return
+		}
+		if err := validRedirectURI(uri); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var secret string
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		secret = oauthClientSecretPrefix + auth.MakeRefreshToken()
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.db.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      userID,
+		Name:         name,
+		SecretHash:   secretHash,
+		RedirectUris: strings.Join(params.RedirectURIs, " "),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := oauthClientResponse(client)
+	response.Secret = secret
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (cfg *apiConfig) handlerOAuthClientsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	clients, err := cfg.db.GetOAuthClientsByOwner(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]OAuthClient, len(clients))
+	for i, client := range clients {
+		response[i] = oauthClientResponse(client)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// handlerOAuthClientDelete unregisters a client. Its codes and refresh
+// tokens go with it, so every user is signed out of the app.
+func (cfg *apiConfig) handlerOAuthClientDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	clientID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	deleted, err := cfg.db.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Client not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type authorizationRequest struct {
+	client        database.OauthClient
+	redirectURI   string
+	scopes        []string
+	state         string
+	codeChallenge string
+}
+
+// redirect builds the URI the user agent is sent back to the client with.
+func (req authorizationRequest) redirect(params url.Values) string {
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+	uri, _ := url.Parse(req.redirectURI)
+	query := uri.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	uri.RawQuery = query.Encode()
+	return uri.String()
+}
+
+// parseAuthorizationRequest checks the query of an authorization request.
+// Errors before the redirect URI is known to belong to the client are
+// shown to the user; later ones also come with the URI to send them back
+// to the client with.
+func (cfg *apiConfig) parseAuthorizationRequest(r *http.Request) (authorizationRequest, *oauthError, string) {
+	query := r.URL.Query()
+	req := authorizationRequest{state: query.Get("state")}
+
+	clientID, err := uuid.Parse(query.Get("client_id"))
+	if err != nil {
+		return req, &oauthError{"invalid_request", "Invalid client_id"}, ""
+	}
+	req.client, err = cfg.db.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		return req, &oauthError{"invalid_request", "Unknown client"}, ""
+	}
+	req.redirectURI = query.Get("redirect_uri")
+	if !slices.Contains(strings.Fields(req.client.RedirectUris), req.redirectURI) {
+		return req, &oauthError{"invalid_request", "redirect_uri isn't registered for this client"}, ""
+	}
+
+	fail := func(code, description string) (authorizationRequest, *oauthError, string) {
+		err := &oauthError{code, description}
+		return req, err, req.redirect(url.Values{"error": {code}, "error_description": {description}})
+	}
+	if query.Get("response_type") != "code" {
+		return fail("unsupported_response_type", "Only the code response type is supported")
+	}
+	req.scopes = strings.Fields(query.Get("scope"))
+	if len(req.scopes) == 0 {
+		return fail("invalid_scope", "Ask for at least one scope")
+	}
+	for _, scope := range req.scopes {
+		if !slices.Contains(apiTokenScopes, scope) {
+			return fail("invalid_scope", "Unknown scope "+scope)
+		}
+	}
+	req.codeChallenge = query.Get("code_challenge")
+	if query.Get("code_challenge_method") != "S256" || len(req.codeChallenge) != 43 {
+		return fail("invalid_request", "PKCE with an S256 code_challenge is required")
+	}
+
+	return req, nil, ""
+}
+
+// handlerOAuthAuthorizeInfo describes an authorization request for the
+// consent screen at /app/oauth/authorize.html, which passes on its query.
+func (cfg *apiConfig) handlerOAuthAuthorizeInfo(w http.ResponseWriter, r *http.Request) {
+	req, oauthErr, redirectTo := cfg.parseAuthorizationRequest(r)
+	if oauthErr != nil {
+		respondWithJSON(w, http.StatusBadRequest, map[string]any{
+			"error":       oauthErr.Description,
+			"redirect_to": redirectTo,
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"client_id":   req.client.ID,
+		"client_name": req.client.Name,
+		"scopes":      req.scopes,
+	})
+}
+
+// handlerOAuthAuthorize records the signed-in user's answer on the consent
+// screen. It needs a first-party access token, so only Chirpy itself can
+// grant access, and answers with where to send the user agent next.
+func (cfg *apiConfig) handlerOAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	req, oauthErr, _ := cfg.parseAuthorizationRequest(r)
+	if oauthErr != nil {
+		respondWithError(w, http.StatusBadRequest, oauthErr.Description)
+		return
+	}
+
+	type parameters struct {
+		Approved bool `json:"approved"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+
+	if !params.Approved {
+		respondWithJSON(w, http.StatusOK, map[string]any{
+			"redirect_to": req.redirect(url.Values{"error": {"access_denied"}}),
+		})
+		return
+	}
+
+	code := auth.MakeRefreshToken()
+	err = cfg.db.CreateOAuthCode(r.Context(), database.CreateOAuthCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      req.client.ID,
+		UserID:        userID,
+		RedirectUri:   req.redirectURI,
+		Scopes:        strings.Join(req.scopes, " "),
+		CodeChallenge: req.codeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(oauthCodeLifetime),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"redirect_to": req.redirect(url.Values{"code": {code}}),
+	})
+}
+
+// authenticateOAuthClient identifies the client calling the token or
+// revocation endpoint, with HTTP Basic or form credentials. Public clients
+// only send their ID; confidential ones must prove they hold the secret.
+func (cfg *apiConfig) authenticateOAuthClient(r *http.Request) (database.OauthClient, *oauthError) {
+	rawID, secret, basic := r.BasicAuth()
+	if !basic {
+		rawID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	invalid := &oauthError{"invalid_client", "Client authentication failed"}
+
+	clientID, err := uuid.Parse(rawID)
+	if err != nil {
+		return database.OauthClient{}, invalid
+	}
+	client, err := cfg.db.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		return database.OauthClient{}, invalid
+	}
+	if client.SecretHash.Valid && subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, invalid
+	}
+	return client, nil
+}
+
+// handlerOAuthToken is the token endpoint, for the authorization_code and
+// refresh_token grants. Like /api/refresh, refreshing only issues a new
+// access token; the refresh token lasts until it expires or is revoked.
+func (cfg *apiConfig) handlerOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"invalid_request", "Invalid form body"})
+		return
+	}
+	client, oauthErr := cfg.authenticateOAuthClient(r)
+	if oauthErr != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		respondWithOAuthError(w, http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	var userID uuid.UUID
+	var scopes []string
+	var refreshToken string
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code, err := cfg.db.ConsumeOAuthCode(r.Context(), database.ConsumeOAuthCodeParams{
+			CodeHash: auth.HashToken(r.PostForm.Get("code")),
+			ClientID: client.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"invalid_grant", "Invalid or already used authorization code"})
+			return
+		}
+		if err != nil {
+			respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{"server_error", err.Error()})
+			return
+		}
+		if !code.ExpiresAt.After(time.Now().UTC()) {
+			respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"invalid_grant", "Authorization code expired"})
+			return
+		}
+		if r.PostForm.Get("redirect_uri") != code.RedirectUri {
+			respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"invalid_grant", "redirect_uri doesn't match the authorization request"})
+			return
+		}
+		if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+			respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"invalid_grant", "Invalid code_verifier"})
+			return
+		}
+		userID, scopes = code.UserID, strings.Fields(code.Scopes)
+
+		refreshToken = auth.MakeRefreshToken()
+		_, err = cfg.db.CreateOAuthRefreshToken(r.Context(), database.CreateOAuthRefreshTokenParams{
+			Token:         refreshToken,
+			UserID:        userID,
+			ExpiresAt:     time.Now().UTC().Add(cfg.lifetimes.Refresh),
+			UserAgent:     userAgent(r),
+			IpAddress:     cfg.clientIP(r),
+			OauthClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+			Scopes:        code.Scopes,
+		})
+		if err != nil {
+			respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{"server_error", err.Error()})
+			return
+		}
+
+	case "refresh_token":
+		token := r.PostForm.Get("refresh_token")
+		grant, err := cfg.db.GetOAuthRefreshToken(r.Context(), database.GetOAuthRefreshTokenParams{
+			Token:         token,
+			OauthClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+			Now:           time.Now().UTC(),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"invalid_grant", "Invalid, expired or revoked refresh token"})
+			return
+		}
+		if err != nil {
+			respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{"server_error", err.Error()})
+			return
+		}
+		userID, scopes = grant.UserID, strings.Fields(grant.Scopes)
+		// A client may ask for fewer scopes than it was granted, never more.
+		if requested := strings.Fields(r.PostForm.Get("scope")); len(requested) > 0 {
+			for _, scope := range requested {
+				if !slices.Contains(scopes, scope) {
+					respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"invalid_scope", "Scope " + scope + " wasn't granted"})
+					return
+				}
+			}
+			scopes = requested
+		}
+		err = cfg.db.TouchRefreshToken(r.Context(), database.TouchRefreshTokenParams{
+			Token:     token,
+			IpAddress: cfg.clientIP(r),
+		})
+		if err != nil {
+			respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{"server_error", err.Error()})
+			return
+		}
+
+	default:
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"unsupported_grant_type", "grant_type must be authorization_code or refresh_token"})
+		return
+	}
+
+	accessToken, err := auth.MakeClientJWT(userID, cfg.keys, cfg.lifetimes.Access, client.ID.String(), scopes)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{"server_error", err.Error()})
+		return
+	}
+
+	response := map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(cfg.lifetimes.Access.Seconds()),
+		"scope":        strings.Join(scopes, " "),
+	}
+	if refreshToken != "" {
+		response["refresh_token"] = refreshToken
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// handlerOAuthRevoke revokes a client's refresh token (RFC 7009). Access
+// tokens are short-lived JWTs and can't be revoked on their own.
+func (cfg *apiConfig) handlerOAuthRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"invalid_request", "Invalid form body"})
+		return
+	}
+	client, oauthErr := cfg.authenticateOAuthClient(r)
+	if oauthErr != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		respondWithOAuthError(w, http.StatusUnauthorized, oauthErr)
+		return
+	}
+	if r.PostForm.Get("token_type_hint") == "access_token" {
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{"unsupported_token_type", "Access tokens can't be revoked, they expire on their own"})
+		return
+	}
+
+	err := cfg.db.RevokeOAuthRefreshToken(r.Context(), database.RevokeOAuthRefreshTokenParams{
+		Token:         r.PostForm.Get("token"),
+		OauthClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{"server_error", err.Error()})
+		return
+	}
+
+	// Unknown tokens are answered the same way, as RFC 7009 requires.
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/dipzza/bootdev_chirpy/internal/oidc"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcStateLifetime = 10 * time.Minute
+	oidcStateCookie   = "chirpy_oidc_state"
+)
+
+var (
+	oidcProviderName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+	errExternalEmailUnverified = errors.New("the provider didn't vouch for an email address")
+	errLocalEmailUnverified    = errors.New("an account with this email exists but hasn't verified it")
+)
+
+// newOIDCProviders reads the OpenID Connect providers users can sign in
+// with. OIDC_PROVIDERS lists their names, e.g. "google,gitlab", and each
+// is configured with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID and
+// OIDC_<NAME>_CLIENT_SECRET. Providers must be registered with
+// <BASE_URL>/api/login/oidc/<name>/callback as redirect URI.
+func newOIDCProviders(baseURL string) (map[string]*oidc.Provider, error) {
+	providers := map[string]*oidc.Provider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !oidcProviderName.MatchString(name) {
+			return nil, fmt.Errorf("invalid OIDC provider name %q", name)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		issuer := os.Getenv(prefix + "ISSUER")
+		clientID := os.Getenv(prefix + "CLIENT_ID")
+		if issuer == "" || clientID == "" {
+			return nil, fmt.Errorf("OIDC provider %s needs %sISSUER and %sCLIENT_ID", name, prefix, prefix)
+		}
+		providers[name] = oidc.NewProvider(issuer, clientID, os.Getenv(prefix+"CLIENT_SECRET"),
+			baseURL+"/api/login/oidc/"+name+"/callback")
+	}
+	return providers, nil
+}
+
+// handlerOIDCLoginBegin sends the browser to the provider to sign in. The
+// state is also set as a cookie, so the callback only completes a login in
+// the browser that started it.
+func (cfg *apiConfig) handlerOIDCLoginBegin(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	provider, ok := cfg.oidcProviders[name]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown sign-in provider")
+		return
+	}
+
+	state := auth.MakeRefreshToken()
+	nonce := auth.MakeRefreshToken()
+	verifier := auth.MakeRefreshToken()
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("oidc: %s", err)
+		respondWithError(w, http.StatusBadGateway, "Couldn't reach the sign-in provider")
+		return
+	}
+	err = cfg.db.CreateOIDCState(r.Context(), database.CreateOIDCStateParams{
+		StateHash:    auth.HashToken(state),
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().UTC().Add(oidcStateLifetime),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/login/oidc",
+		MaxAge:   int(oidcStateLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (cfg *apiConfig) handlerOIDCLoginCallback(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	provider, ok := cfg.oidcProviders[name]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown sign-in provider")
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		respondWithError(w, http.StatusUnauthorized, "Sign-in was cancelled or failed: "+query.Get("error"))
+		return
+	}
+	state, code := query.Get("state"), query.Get("code")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if state == "" || code == "" || err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired sign-in attempt")
+		return
+	}
+
+	login, err := cfg.db.ConsumeOIDCState(r.Context(), database.ConsumeOIDCStateParams{
+		StateHash: auth.HashToken(state),
+		Provider:  name,
+		Now:       time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired sign-in attempt")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.Printf("oidc: signing in with %s: %s", name, err)
+		respondWithError(w, http.StatusUnauthorized, "Couldn't verify the sign-in with "+name)
+		return
+	}
+
+	user, err := cfg.externalUser(r.Context(), name, claims)
+	if errors.Is(err, errExternalEmailUnverified) {
+		respondWithError(w, http.StatusForbidden, "Your "+name+" account needs a verified email address to sign in to Chirpy")
+		return
+	}
+	if errors.Is(err, errLocalEmailUnverified) {
+		respondWithError(w, http.StatusConflict, "An account with this email already exists. Log in and verify your email before signing in with "+name)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response, err := cfg.completeLogin(r, user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   oidcStateCookie,
+		Path:   "/api/login/oidc",
+		MaxAge: -1,
+	})
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// externalUser returns the user signing in as claims' subject at provider.
+// The first time, the identity is linked to the account with the same
+// email, or to a new account, but only if both sides have verified the
+// address: otherwise whoever registers it first could take over the other
+// account.
+func (cfg *apiConfig) externalUser(ctx context.Context, provider string, claims oidc.Claims) (database.User, error) {
+	identity, err := cfg.db.GetExternalIdentity(ctx, database.GetExternalIdentityParams{
+		Provider: provider,
+		Subject:  claims.Subject,
+	})
+	if err == nil {
+		return cfg.db.GetUserByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return database.User{}, errExternalEmailUnverified
+	}
+
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.GetUser(ctx, claims.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Nobody knows the random password; the account signs in through
+		// the provider, or sets a password with a reset email.
+		hashedPassword, err := auth.HashPassword(auth.MakeRefreshToken())
+		if err != nil {
+			return database.User{}, err
+		}
+		user, err = qtx.CreateUser(ctx, database.CreateUserParams{
+			Email:          claims.Email,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			return database.User{}, err
+		}
+		if _, err := qtx.VerifyUserEmail(ctx, database.VerifyUserEmailParams{
+			ID:    user.ID,
+			Email: user.Email,
+		}); err != nil {
+			return database.User{}, err
+		}
+		user.EmailVerifiedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	} else if err != nil {
+		return database.User{}, err
+	} else if !user.EmailVerifiedAt.Valid {
+		return database.User{}, errLocalEmailUnverified
+	}
+
+	_, err = qtx.CreateExternalIdentity(ctx, database.CreateExternalIdentityParams{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+type ExternalIdentity struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+}
+
+func externalIdentityResponse(identity database.ExternalIdentity) ExternalIdentity {
+	return ExternalIdentity{
+		ID:        identity.ID,
+		CreatedAt: identity.CreatedAt,
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+	}
+}
+
+func (cfg *apiConfig) handlerExternalIdentitiesList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	identities, err := cfg.db.GetExternalIdentities(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := make([]ExternalIdentity, len(identities))
+	for i, identity := range identities {
+		response[i] = externalIdentityResponse(identity)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerExternalIdentityUnlink(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	identityID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	deleted, err := cfg.db.DeleteExternalIdentity(r.Context(), database.DeleteExternalIdentityParams{
+		ID:     identityID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Identity not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/auth"
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/dipzza/bootdev_chirpy/internal/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	ceremonyRegister  = "webauthn.create"
+	ceremonyLogin     = "webauthn.get"
+	ceremonyTimeout   = 5 * time.Minute
+	maxPasskeyNameLen = 50
+)
+
+// newRelyingParty reads WEBAUTHN_RP_ID and WEBAUTHN_ORIGIN, which must
+// match the domain and origin the frontend is served from.
+func newRelyingParty(baseURL string) webauthn.RelyingParty {
+	rp := webauthn.RelyingParty{
+		ID:     os.Getenv("WEBAUTHN_RP_ID"),
+		Name:   "Chirpy",
+		Origin: os.Getenv("WEBAUTHN_ORIGIN"),
+	}
+	if rp.ID == "" {
+		rp.ID = "localhost"
+	}
+	if rp.Origin == "" {
+		rp.Origin = baseURL
+	}
+	return rp
+}
+
+type Passkey struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func passkeyResponse(passkey database.Passkey) Passkey {
+	response := Passkey{
+		ID:        passkey.ID,
+		CreatedAt: passkey.CreatedAt,
+		Name:      passkey.Name,
+	}
+	if passkey.LastUsedAt.Valid {
+		response.LastUsedAt = &passkey.LastUsedAt.Time
+	}
+	return response
+}
+
+// decodeBase64URL accepts the base64url encoding WebAuthn clients use,
+// with or without padding.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func (cfg *apiConfig) newCeremony(ctx context.Context, userID uuid.NullUUID, ceremony string) ([]byte, error) {
+	challenge := webauthn.NewChallenge()
+	err := cfg.db.CreateWebAuthnChallenge(ctx, database.CreateWebAuthnChallengeParams{
+		ChallengeHash: auth.HashToken(string(challenge)),
+		UserID:        userID,
+		Ceremony:      ceremony,
+		ExpiresAt:     time.Now().UTC().Add(ceremonyTimeout),
+	})
+	return challenge, err
+}
+
+// consumeCeremony finds the challenge clientDataJSON answers and deletes
+// it, so each ceremony can complete only once.
+func (cfg *apiConfig) consumeCeremony(ctx context.Context, clientDataJSON []byte, ceremony string) ([]byte, uuid.NullUUID, error) {
+	challenge, err := webauthn.ChallengeFromClientData(clientDataJSON)
+	if err != nil {
+		return nil, uuid.NullUUID{}, err
+	}
+	userID, err := cfg.db.ConsumeWebAuthnChallenge(ctx, database.ConsumeWebAuthnChallengeParams{
+		ChallengeHash: auth.HashToken(string(challenge)),
+		Ceremony:      ceremony,
+		Now:           time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, uuid.NullUUID{}, errors.New("Unknown or expired challenge")
+	}
+	return challenge, userID, err
+}
+
+func (cfg *apiConfig) handlerPasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	passkeys, err := cfg.db.GetPasskeysByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	existing := make([][]byte, len(passkeys))
+	for i, passkey := range passkeys {
+		existing[i] = passkey.CredentialID
+	}
+
+	challenge, err := cfg.newCeremony(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, ceremonyRegister)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"publicKey": cfg.webauthn.CreationOptions(challenge, userID[:], user.Email, existing, ceremonyTimeout),
+	})
+}
+
+func (cfg *apiConfig) handlerPasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	type parameters struct {
+		Name              string `json:"name"`
+		ClientDataJSON    string `json:"client_data_json"`
+		AttestationObject string `json:"attestation_object"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > maxPasskeyNameLen {
+		respondWithError(w, http.StatusBadRequest, "Passkey name must be between 1 and 50 characters")
+		return
+	}
+	clientDataJSON, err := decodeBase64URL(params.ClientDataJSON)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid client_data_json: "+err.Error())
+		return
+	}
+	attestationObject, err := decodeBase64URL(params.AttestationObject)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid attestation_object: "+err.Error())
+		return
+	}
+
+	challenge, challengeUser, err := cfg.consumeCeremony(r.Context(), clientDataJSON, ceremonyRegister)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if challengeUser.UUID != userID {
+		respondWithError(w, http.StatusBadRequest, "Unknown or expired challenge")
+		return
+	}
+	credential, err := cfg.webauthn.VerifyRegistration(clientDataJSON, attestationObject, challenge)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	passkey, err := cfg.db.CreatePasskey(r.Context(), database.CreatePasskeyParams{
+		UserID:       userID,
+		Name:         name,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "This passkey is already registered")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, passkeyResponse(passkey))
+}
+
+func (cfg *apiConfig) handlerPasskeysList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	passkeys, err := cfg.db.GetPasskeysByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response := make([]Passkey, len(passkeys))
+	for i, passkey := range passkeys {
+		response[i] = passkeyResponse(passkey)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerPasskeyRename(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	passkeyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	type parameters struct {
+		Name string `json:"name"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+	name := strings.TrimSpace(params.Name)
+	if name == "" || len(name) > maxPasskeyNameLen {
+		respondWithError(w, http.StatusBadRequest, "Passkey name must be between 1 and 50 characters")
+		return
+	}
+
+	passkey, err := cfg.db.RenamePasskey(r.Context(), database.RenamePasskeyParams{
+		Name:   name,
+		ID:     passkeyID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Passkey not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, passkeyResponse(passkey))
+}
+
+func (cfg *apiConfig) handlerPasskeyDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	passkeyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	deleted, err := cfg.db.DeletePasskey(r.Context(), database.DeletePasskeyParams{
+		ID:     passkeyID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Passkey not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerPasskeyLoginBegin starts a passkey login. Without an email the
+// browser offers every discoverable passkey it holds for the site.
+// decoyCredentialID is the credential ID offered for an email without
+// passkeys. It is derived from the address so that asking twice gives the
+// same answer, as it would for a real passkey.
+func (cfg *apiConfig) decoyCredentialID(email string) []byte {
+	mac := hmac.New(sha256.New, []byte(cfg.emailTokenSecret))
+	mac.Write([]byte("chirpy passkey decoy\x00" + loginEmail(email)))
+	return mac.Sum(nil)
+}
+
+func (cfg *apiConfig) handlerPasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+	params := parameters{}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		if err := decoder.Decode(&params); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+			return
+		}
+	}
+
+	var allowed [][]byte
+	if params.Email != "" {
+		user, err := cfg.db.GetUser(r.Context(), params.Email)
+		if err == nil {
+			passkeys, err := cfg.db.GetPasskeysByUser(r.Context(), user.ID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			for _, passkey := range passkeys {
+				allowed = append(allowed, passkey.CredentialID)
+			}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// Unknown addresses and accounts without passkeys get a made-up
+		// credential rather than an error or an empty list, so this can't
+		// be used to find out who has an account or a passkey.
+		if len(allowed) == 0 {
+			allowed = [][]byte{cfg.decoyCredentialID(params.Email)}
+		}
+	}
+
+	challenge, err := cfg.newCeremony(r.Context(), uuid.NullUUID{}, ceremonyLogin)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"publicKey": cfg.webauthn.RequestOptions(challenge, allowed, ceremonyTimeout),
+	})
+}
+
+func (cfg *apiConfig) handlerPasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		CredentialID      string `json:"credential_id"`
+		ClientDataJSON    string `json:"client_data_json"`
+		AuthenticatorData string `json:"authenticator_data"`
+		Signature         string `json:"signature"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+	var fields [4][]byte
+	for i, field := range []string{params.CredentialID, params.ClientDataJSON, params.AuthenticatorData, params.Signature} {
+		decoded, err := decodeBase64URL(field)
+		if err != nil || len(decoded) == 0 {
+			respondWithError(w, http.StatusBadRequest, "credential_id, client_data_json, authenticator_data and signature must be base64url encoded")
+			return
+		}
+		fields[i] = decoded
+	}
+	credentialID, clientDataJSON, authenticatorData, signature := fields[0], fields[1], fields[2], fields[3]
+
+	challenge, _, err := cfg.consumeCeremony(r.Context(), clientDataJSON, ceremonyLogin)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	passkey, err := cfg.db.GetPasskeyByCredentialID(r.Context(), credentialID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Unknown passkey")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	assertion, err := cfg.webauthn.VerifyAssertion(clientDataJSON, authenticatorData, signature, challenge, webauthn.Credential{
+		ID:        passkey.CredentialID,
+		PublicKey: passkey.PublicKey,
+		SignCount: uint32(passkey.SignCount),
+	})
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+	err = cfg.db.UpdatePasskeyUsage(r.Context(), database.UpdatePasskeyUsageParams{
+		ID:        passkey.ID,
+		SignCount: int64(assertion.SignCount),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), passkey.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+	// A passkey the device unlocked with a PIN or biometrics is two factors
+	// on its own. A security key that was only touched is one, so accounts
+	// with TOTP still get the challenge after it.
+	var response map[string]any
+	if assertion.UserVerified {
+		response, err = cfg.loginResponse(r, user)
+	} else {
+		response, err = cfg.completeLogin(r, user)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
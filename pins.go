@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxPinnedChirps = 3
+
+// pinnedChirps returns the chirps authorID has pinned that viewer can see,
+// most recently pinned first.
+func (cfg *apiConfig) pinnedChirps(ctx context.Context, authorID, viewer uuid.UUID) ([]database.Chirp, error) {
+	ids, err := cfg.db.GetPinnedChirpIDs(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	chirps, err := cfg.db.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	chirps, err = cfg.visibleChirps(ctx, chirps, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]database.Chirp, len(chirps))
+	for _, chirp := range chirps {
+		byID[chirp.ID] = chirp
+	}
+	pinned := []database.Chirp{}
+	for _, id := range ids {
+		chirp, ok := byID[id]
+		if ok {
+			pinned = append(pinned, chirp)
+		}
+	}
+
+	return pinned, nil
+}
+
+func (cfg *apiConfig) handlerChirpPin(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "You can only pin your own chirps")
+		return
+	}
+
+	// Pins are counted and added with the user's row locked, so concurrent
+	// requests can't each see room for one more pin.
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if err := qtx.LockUserPins(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pinnedIDs, err := qtx.GetPinnedChirpIDs(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, id := range pinnedIDs {
+		if id == chirpID {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	pinned, err := qtx.PinChirp(r.Context(), database.PinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+		MaxPins: maxPinnedChirps,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if pinned == 0 {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("You can't pin more than %d chirps", maxPinnedChirps))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerChirpUnpin(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	unpinned, err := cfg.db.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if unpinned == 0 {
+		respondWithError(w, http.StatusNotFound, "Chirp is not pinned")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
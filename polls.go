@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/dipzza/bootdev_chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	minPollOptions   = 2
+	maxPollOptions   = 4
+	maxPollOptionLen = 25
+	maxPollDuration  = 7 * 24 * time.Hour
+)
+
+type pollParameters struct {
+	Options   []string  `json:"options"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// validate checks the poll of a chirp that will be published at start.
+func (p pollParameters) validate(start time.Time) error {
+	if len(p.Options) < minPollOptions || len(p.Options) > maxPollOptions {
+		return fmt.Errorf("A poll needs between %d and %d options", minPollOptions, maxPollOptions)
+	}
+	for _, option := range p.Options {
+		if strings.TrimSpace(option) == "" {
+			return errors.New("Poll options can't be empty")
+		}
+		if len(option) > maxPollOptionLen {
+			return fmt.Errorf("Poll options can't be longer than %d characters", maxPollOptionLen)
+		}
+	}
+
+	if !p.ExpiresAt.After(start) {
+		return errors.New("Poll expiry must be after the chirp is published")
+	}
+	if p.ExpiresAt.After(start.Add(maxPollDuration)) {
+		return fmt.Errorf("Polls can't run for longer than %s", maxPollDuration)
+	}
+
+	return nil
+}
+
+// moderate runs every option through the moderation pipeline the chirp
+// body goes through, replacing them with their masked labels. The returned
+// decision holds or flags the chirp if any option should, and rejects it
+// if any option is rejected.
+func (p *pollParameters) moderate(ctx context.Context, moderator *moderation.Pipeline) (moderation.Decision, error) {
+	combined := moderation.Decision{}
+	for i, option := range p.Options {
+		decision, err := moderator.Run(ctx, option)
+		if err != nil {
+			return moderation.Decision{}, err
+		}
+		for _, reason := range decision.Reasons {
+			combined.Reasons = append(combined.Reasons, fmt.Sprintf("poll option %d: %s", i+1, reason))
+		}
+		if decision.Rejected {
+			combined.Rejected = true
+			return combined, nil
+		}
+		combined.Flagged = combined.Flagged || decision.Flagged
+		combined.Held = combined.Held || decision.Held
+		p.Options[i] = decision.Body
+	}
+	return combined, nil
+}
+
+type PollOption struct {
+	Label string `json:"label"`
+	// Votes is only set once the viewer has voted or the poll has closed.
+	Votes *int64 `json:"votes,omitempty"`
+}
+
+type Poll struct {
+	ExpiresAt   time.Time    `json:"expires_at"`
+	Closed      bool         `json:"closed"`
+	Options     []PollOption `json:"options"`
+	VotedOption *int32       `json:"voted_option"`
+	TotalVotes  *int64       `json:"total_votes,omitempty"`
+}
+
+func createPoll(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID, params pollParameters) error {
+	_, err := qtx.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:   chirpID,
+		ExpiresAt: params.ExpiresAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, option := range params.Options {
+		err := qtx.CreatePollOption(ctx, database.CreatePollOptionParams{
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Label:    strings.TrimSpace(option),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pollsForChirps returns the polls attached to any of the given chirps,
+// with tallies revealed according to what viewer is allowed to see.
+func (cfg *apiConfig) pollsForChirps(ctx context.Context, chirpIDs []uuid.UUID, viewer uuid.UUID) (map[uuid.UUID]*Poll, error) {
+	polls, err := cfg.db.GetPollsForChirps(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(polls) == 0 {
+		return map[uuid.UUID]*Poll{}, nil
+	}
+
+	pollIDs := make([]uuid.UUID, len(polls))
+	for i, poll := range polls {
+		pollIDs[i] = poll.ChirpID
+	}
+	options, err := cfg.db.GetPollOptionsForChirps(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	tallies, err := cfg.db.GetPollTalliesForChirps(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	votes := map[uuid.UUID]int32{}
+	if viewer != uuid.Nil {
+		viewerVotes, err := cfg.db.GetUserPollVotesForChirps(ctx, database.GetUserPollVotesForChirpsParams{
+			UserID:   viewer,
+			ChirpIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range viewerVotes {
+			votes[vote.ChirpID] = vote.Position
+		}
+	}
+
+	now := time.Now().UTC()
+	response := make(map[uuid.UUID]*Poll, len(polls))
+	for _, poll := range polls {
+		response[poll.ChirpID] = &Poll{
+			ExpiresAt: poll.ExpiresAt,
+			Closed:    !poll.ExpiresAt.After(now),
+			Options:   []PollOption{},
+		}
+		if position, ok := votes[poll.ChirpID]; ok {
+			response[poll.ChirpID].VotedOption = &position
+		}
+	}
+	for _, option := range options {
+		poll := response[option.ChirpID]
+		poll.Options = append(poll.Options, PollOption{Label: option.Label})
+	}
+	for _, poll := range response {
+		if !poll.Closed && poll.VotedOption == nil {
+			continue
+		}
+		var total int64
+		for i := range poll.Options {
+			poll.Options[i].Votes = new(int64)
+		}
+		poll.TotalVotes = &total
+	}
+	for _, tally := range tallies {
+		poll := response[tally.ChirpID]
+		if poll.TotalVotes == nil || int(tally.Position) >= len(poll.Options) {
+			continue
+		}
+		*poll.Options[tally.Position].Votes = tally.Votes
+		*poll.TotalVotes += tally.Votes
+	}
+
+	return response, nil
+}
+
+func (cfg *apiConfig) handlerPollVote(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+
+	type parameters struct {
+		Option *int32 `json:"option"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+	if params.Option == nil {
+		respondWithError(w, http.StatusBadRequest, "Missing option")
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || !chirp.Published {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+	visible, err := cfg.chirpVisibleTo(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !visible {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	poll, err := cfg.db.GetPoll(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp has no poll")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !poll.ExpiresAt.After(time.Now().UTC()) {
+		respondWithError(w, http.StatusConflict, "Poll is closed")
+		return
+	}
+
+	voted, err := cfg.db.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+		ChirpID:  chirpID,
+		UserID:   userID,
+		Position: *params.Option,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusBadRequest, "Invalid option")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if voted == 0 {
+		respondWithError(w, http.StatusConflict, "Already voted in this poll")
+		return
+	}
+
+	response, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
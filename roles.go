@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+// roleRanks orders the roles: each one can do everything the roles below
+// it can.
+var roleRanks = map[string]int{
+	roleUser:      0,
+	roleModerator: 1,
+	roleAdmin:     2,
+}
+
+const (
+	defaultRoleChangesLimit = 50
+	maxRoleChangesLimit     = 500
+)
+
+// requireRole only lets users with at least role through to next. The role
+// is looked up on every request rather than carried in the access token,
+// so revoking it takes effect straight away.
+func (cfg *apiConfig) requireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := cfg.authenticatedUser(r)
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+
+		userRole, err := cfg.db.GetUserRole(r.Context(), userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondUnauthorized(w, errors.New("User not found"))
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if roleRanks[userRole] < roleRanks[role] {
+			respondWithError(w, http.StatusForbidden, "This requires the "+role+" role")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bootstrapAdmins gives the admin role to the accounts listed in
+// ADMIN_EMAILS, so a fresh deployment has someone who can grant roles.
+// Accounts that haven't verified their email are skipped, since anyone
+// can sign up with an address they don't own.
+func (cfg *apiConfig) bootstrapAdmins(ctx context.Context) {
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		user, err := cfg.db.GetUser(ctx, email)
+		if err != nil {
+			log.Printf("bootstrapping admin %s: %s", email, err)
+			continue
+		}
+		if !user.EmailVerifiedAt.Valid {
+			log.Printf("bootstrapping admin %s: email is not verified", email)
+			continue
+		}
+		if user.Role == roleAdmin {
+			continue
+		}
+		if _, err := cfg.changeRole(ctx, user.ID, uuid.Nil, roleAdmin); err != nil {
+			log.Printf("bootstrapping admin %s: %s", email, err)
+		}
+	}
+}
+
+// changeRole sets userID's role and records who changed it from what in
+// the audit trail. changedBy is uuid.Nil for changes made by the server
+// itself.
+func (cfg *apiConfig) changeRole(ctx context.Context, userID, changedBy uuid.UUID, role string) (database.RoleChange, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.RoleChange{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// The row stays locked until commit, so concurrent changes can't both
+	// record the same old role.
+	oldRole, err := qtx.GetUserRoleForUpdate(ctx, userID)
+	if err != nil {
+		return database.RoleChange{}, err
+	}
+	if _, err := qtx.SetUserRole(ctx, database.SetUserRoleParams{ID: userID, Role: role}); err != nil {
+		return database.RoleChange{}, err
+	}
+	change, err := qtx.CreateRoleChange(ctx, database.CreateRoleChangeParams{
+		UserID:    uuid.NullUUID{UUID: userID, Valid: true},
+		ChangedBy: uuid.NullUUID{UUID: changedBy, Valid: changedBy != uuid.Nil},
+		OldRole:   oldRole,
+		NewRole:   role,
+	})
+	if err != nil {
+		return database.RoleChange{}, err
+	}
+
+	return change, tx.Commit()
+}
+
+// handlerUserRoleUpdate grants or revokes a role. Admins can't change
+// their own role, so there is always someone left who can undo a mistake.
+func (cfg *apiConfig) handlerUserRoleUpdate(w http.ResponseWriter, r *http.Request) {
+	adminID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+err.Error())
+		return
+	}
+	if userID == adminID {
+		respondWithError(w, http.StatusForbidden, "You can't change your own role")
+		return
+	}
+
+	type parameters struct {
+		Role string `json:"role"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON:"+err.Error())
+		return
+	}
+	if _, ok := roleRanks[params.Role]; !ok {
+		respondWithError(w, http.StatusBadRequest, "Role must be user, moderator or admin")
+		return
+	}
+
+	change, err := cfg.changeRole(r.Context(), userID, adminID, params.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, change)
+}
+
+// handlerRoleChangesList returns the audit trail of role changes, newest
+// first, optionally only for the user given as user_id.
+func (cfg *apiConfig) handlerRoleChangesList(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRoleChangesLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		var err error
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxRoleChangesLimit {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	var changes []database.RoleChange
+	var err error
+	if param := r.URL.Query().Get("user_id"); param != "" {
+		userID, parseErr := uuid.Parse(param)
+		if parseErr != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid UUID:"+parseErr.Error())
+			return
+		}
+		changes, err = cfg.db.GetUserRoleChanges(r.Context(), database.GetUserRoleChangesParams{
+			UserID: uuid.NullUUID{UUID: userID, Valid: true},
+			Limit:  int32(limit),
+		})
+	} else {
+		changes, err = cfg.db.GetRoleChanges(r.Context(), int32(limit))
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if changes == nil {
+		changes = []database.RoleChange{}
+	}
+
+	respondWithJSON(w, http.StatusOK, changes)
+}
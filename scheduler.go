@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+const schedulerInterval = 15 * time.Second
+
+// runScheduler publishes scheduled chirps once their publish_at has passed,
+// deletes accounts at the end of their grace period and uploads that were
+// never attached to a chirp, and clears out unused OAuth authorization
+// codes, OpenID Connect login states and old failed logins. All state
+// lives in the database, so work that became due while the server was down
+// is done on the first tick after a restart.
+func (cfg *apiConfig) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		chirps, err := cfg.db.PublishDueChirps(ctx, time.Now().UTC())
+		if err != nil {
+			log.Printf("scheduler: publishing due chirps: %s", err)
+		}
+		for _, chirp := range chirps {
+			log.Printf("scheduler: published chirp %s", chirp.ID)
+		}
+		cfg.purgeDeletedAccounts(ctx)
+		cfg.purgeUnattachedMedia(ctx)
+		if err := cfg.db.DeleteExpiredOAuthCodes(ctx, time.Now().UTC()); err != nil {
+			log.Printf("scheduler: deleting expired OAuth codes: %s", err)
+		}
+		if err := cfg.db.DeleteExpiredOIDCStates(ctx, time.Now().UTC()); err != nil {
+			log.Printf("scheduler: deleting expired OIDC states: %s", err)
+		}
+		cfg.purgeLoginFailures(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) handlerScheduledChirpsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	chirps, err := cfg.db.GetScheduledChirpsByAuthor(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response, err := cfg.chirpsResponse(r.Context(), chirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,30 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, scopes, expires_at)
+VALUES (
+  gen_random_uuid(),
+  now(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+RETURNING *;
+
+-- name: GetAPITokenByHash :one
+SELECT * FROM api_tokens
+WHERE token_hash = $1;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET last_used_at = now()
+WHERE id = $1;
+
+-- name: GetAPITokens :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE id = $1 AND user_id = $2;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- name: GetBannedWords :many
+SELECT word FROM banned_words;
\ No newline at end of file
@@ -0,0 +1,58 @@
+-- name: CreateBookmarkCollection :one
+INSERT INTO bookmark_collections (id, created_at, updated_at, user_id, name)
+VALUES (
+  gen_random_uuid(),
+  now(),
+  now(),
+  $1,
+  $2
+)
+RETURNING *;
+
+-- name: GetBookmarkCollection :one
+SELECT * FROM bookmark_collections
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetBookmarkCollectionsByUser :many
+SELECT * FROM bookmark_collections
+WHERE user_id = $1
+ORDER BY name ASC;
+
+-- name: RenameBookmarkCollection :one
+UPDATE bookmark_collections
+SET name = $1, updated_at = now()
+WHERE id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: DeleteBookmarkCollection :execrows
+DELETE FROM bookmark_collections
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateBookmark :execrows
+INSERT INTO bookmarks (id, created_at, user_id, chirp_id, collection_id)
+VALUES (
+  gen_random_uuid(),
+  now(),
+  $1,
+  $2,
+  $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetBookmarksByUser :many
+SELECT * FROM bookmarks
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetBookmarksInCollection :many
+SELECT * FROM bookmarks
+WHERE user_id = $1 AND collection_id = $2
+ORDER BY created_at DESC;
+
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: DeleteBookmarkFromCollection :execrows
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2 AND collection_id = $3;
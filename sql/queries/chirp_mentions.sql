@@ -0,0 +1,15 @@
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES (
+  $1,
+  $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetMentionsForChirps :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetMentionedAmong :many
+SELECT chirp_id FROM chirp_mentions
+WHERE user_id = sqlc.arg(user_id) AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
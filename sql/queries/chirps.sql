@@ -1,11 +1,16 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, flagged, held, publish_at, published, visibility)
 VALUES (
   gen_random_uuid(),
   now(),
   now(),
   $1,
-  $2
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7
 )
 RETURNING *;
 
@@ -14,8 +19,53 @@ SELECT * FROM chirps
 WHERE id = $1;
 
 -- name: GetAllChirps :many
-SELECT * FROM chirps;
+SELECT * FROM chirps
+WHERE held = false AND published = true;
 
 -- name: GetChirpsByAuthor :many
 SELECT * FROM chirps
-WHERE user_id = $1;
\ No newline at end of file
+WHERE user_id = $1 AND held = false AND published = true;
+
+-- name: GetHeldChirps :many
+SELECT * FROM chirps
+WHERE held = true
+ORDER BY created_at ASC;
+
+-- name: ApproveChirp :one
+UPDATE chirps
+SET held = false, updated_at = now()
+WHERE id = $1 AND held = true
+RETURNING *;
+
+-- name: DeleteChirp :exec
+DELETE FROM chirps
+WHERE id = $1;
+
+
+-- name: GetScheduledChirpsByAuthor :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND published = false
+ORDER BY publish_at ASC;
+
+-- name: PublishDueChirps :many
+UPDATE chirps
+SET published = true, updated_at = now()
+WHERE published = false AND publish_at <= sqlc.arg(now)::timestamp
+RETURNING *;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetTimelineChirps :many
+SELECT * FROM chirps
+WHERE user_id = ANY(sqlc.arg(user_ids)::uuid[])
+  AND held = false AND published = true
+  AND (publish_at, id) < (sqlc.arg(before_publish_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY publish_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetOwnChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at ASC;
\ No newline at end of file
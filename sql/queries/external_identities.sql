@@ -0,0 +1,44 @@
+-- name: CreateExternalIdentity :one
+INSERT INTO external_identities (id, created_at, user_id, provider, subject, email)
+VALUES (
+  gen_random_uuid(),
+  now(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: GetExternalIdentity :one
+SELECT * FROM external_identities
+WHERE provider = $1 AND subject = $2;
+
+-- name: GetExternalIdentities :many
+SELECT * FROM external_identities
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteExternalIdentity :execrows
+DELETE FROM external_identities
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateOIDCState :exec
+INSERT INTO oidc_states (state_hash, created_at, provider, nonce, code_verifier, expires_at)
+VALUES (
+  $1,
+  now(),
+  $2,
+  $3,
+  $4,
+  $5
+);
+
+-- name: ConsumeOIDCState :one
+DELETE FROM oidc_states
+WHERE state_hash = sqlc.arg(state_hash) AND provider = sqlc.arg(provider) AND expires_at > sqlc.arg(now)::timestamp
+RETURNING *;
+
+-- name: DeleteExpiredOIDCStates :exec
+DELETE FROM oidc_states
+WHERE expires_at < sqlc.arg(now)::timestamp;
\ No newline at end of file
@@ -0,0 +1,43 @@
+-- name: FollowUser :one
+INSERT INTO follows (follower_id, followee_id, created_at, approved)
+SELECT sqlc.arg(follower_id), id, now(), NOT protected
+FROM users
+WHERE id = sqlc.arg(followee_id)
+ON CONFLICT DO NOTHING
+RETURNING approved;
+
+-- name: UnfollowUser :execrows
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowedAmong :many
+SELECT followee_id FROM follows
+WHERE follower_id = sqlc.arg(follower_id) AND approved = true AND followee_id = ANY(sqlc.arg(user_ids)::uuid[]);
+
+-- name: GetFollowRequests :many
+SELECT follower_id, created_at FROM follows
+WHERE followee_id = $1 AND approved = false
+ORDER BY created_at ASC;
+
+-- name: ApproveFollowRequest :execrows
+UPDATE follows
+SET approved = true
+WHERE follower_id = $1 AND followee_id = $2 AND approved = false;
+
+-- name: DenyFollowRequest :execrows
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2 AND approved = false;
+
+-- name: ApproveAllFollowRequests :exec
+UPDATE follows
+SET approved = true
+WHERE followee_id = $1 AND approved = false;
+
+-- name: GetFollowees :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1 AND approved = true;
+
+-- name: GetFollowsByUser :many
+SELECT * FROM follows
+WHERE follower_id = $1 OR followee_id = $1
+ORDER BY created_at ASC;
\ No newline at end of file
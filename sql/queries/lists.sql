@@ -0,0 +1,48 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, user_id, name, private)
+VALUES (
+  gen_random_uuid(),
+  now(),
+  now(),
+  $1,
+  $2,
+  $3
+)
+RETURNING *;
+
+-- name: GetList :one
+SELECT * FROM lists
+WHERE id = $1;
+
+-- name: GetListsByUser :many
+SELECT * FROM lists
+WHERE user_id = $1
+ORDER BY name ASC;
+
+-- name: UpdateList :one
+UPDATE lists
+SET name = $1, private = $2, updated_at = now()
+WHERE id = $3 AND user_id = $4
+RETURNING *;
+
+-- name: DeleteList :execrows
+DELETE FROM lists
+WHERE id = $1 AND user_id = $2;
+
+-- name: AddListMember :execrows
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES (
+  $1,
+  $2,
+  now()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveListMember :execrows
+DELETE FROM list_members
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: GetListMembers :many
+SELECT user_id FROM list_members
+WHERE list_id = $1
+ORDER BY created_at ASC;
\ No newline at end of file
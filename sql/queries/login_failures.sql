@@ -0,0 +1,63 @@
+-- name: CreateLoginIPFailures :exec
+INSERT INTO login_ip_failures (ip_address, window_started_at, failures)
+VALUES (sqlc.arg(ip_address), sqlc.arg(now)::timestamp, 0)
+ON CONFLICT (ip_address) DO NOTHING;
+
+-- name: GetLoginIPFailuresForUpdate :one
+SELECT * FROM login_ip_failures
+WHERE ip_address = $1
+FOR UPDATE;
+
+-- name: RecordLoginIPFailure :one
+UPDATE login_ip_failures
+SET failures = CASE WHEN window_started_at < sqlc.arg(window_start)::timestamp THEN 1 ELSE failures + 1 END,
+  window_started_at = CASE WHEN window_started_at < sqlc.arg(window_start)::timestamp THEN sqlc.arg(now)::timestamp ELSE window_started_at END
+WHERE ip_address = sqlc.arg(ip_address)
+RETURNING failures;
+
+-- name: ForgetLoginIPFailure :exec
+UPDATE login_ip_failures
+SET failures = failures - 1
+WHERE ip_address = $1 AND failures > 0;
+
+-- name: LockLoginIP :exec
+UPDATE login_ip_failures
+SET locked_until = $2
+WHERE ip_address = $1;
+
+-- name: DeleteLoginIPFailures :execrows
+DELETE FROM login_ip_failures
+WHERE ip_address = $1;
+
+-- name: DeleteStaleLoginIPFailures :exec
+DELETE FROM login_ip_failures
+WHERE window_started_at < sqlc.arg(window_start)::timestamp AND (locked_until IS NULL OR locked_until < sqlc.arg(now)::timestamp);
+
+-- name: CreateLoginEmailFailures :exec
+INSERT INTO login_email_failures (email, last_failed_at, failures)
+VALUES (sqlc.arg(email), sqlc.arg(now)::timestamp, 0)
+ON CONFLICT (email) DO NOTHING;
+
+-- name: GetLoginEmailFailuresForUpdate :one
+SELECT * FROM login_email_failures
+WHERE email = $1
+FOR UPDATE;
+
+-- name: RecordLoginEmailFailure :one
+UPDATE login_email_failures
+SET failures = failures + 1, last_failed_at = sqlc.arg(now)::timestamp
+WHERE email = sqlc.arg(email)
+RETURNING failures;
+
+-- name: LockLoginEmail :exec
+UPDATE login_email_failures
+SET locked_until = $2
+WHERE email = $1;
+
+-- name: DeleteLoginEmailFailures :exec
+DELETE FROM login_email_failures
+WHERE email = $1;
+
+-- name: DeleteStaleLoginEmailFailures :exec
+DELETE FROM login_email_failures
+WHERE last_failed_at < sqlc.arg(last_failed_before)::timestamp AND (locked_until IS NULL OR locked_until < sqlc.arg(now)::timestamp);
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- name: CreateMagicLink :exec
+INSERT INTO magic_links (token_hash, created_at, user_id, device_hash, expires_at)
+VALUES (
+  $1,
+  now(),
+  $2,
+  $3,
+  $4
+);
+
+-- name: ConsumeMagicLink :one
+UPDATE magic_links
+SET used_at = now()
+WHERE token_hash = sqlc.arg(token_hash) AND device_hash = sqlc.arg(device_hash) AND used_at IS NULL AND expires_at > sqlc.arg(now)::timestamp
+RETURNING user_id;
\ No newline at end of file
@@ -0,0 +1,40 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files (id, created_at, updated_at, user_id, content_type, size, width, height, storage_key, thumbnail_key)
+VALUES (
+  $1,
+  now(),
+  now(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8
+)
+RETURNING *;
+
+-- name: GetMediaFile :one
+SELECT * FROM media_files
+WHERE id = $1;
+
+-- name: AttachMediaFile :execrows
+UPDATE media_files
+SET chirp_id = $1, position = $2, updated_at = now()
+WHERE id = $3 AND user_id = $4 AND chirp_id IS NULL;
+
+-- name: GetMediaFilesForChirps :many
+SELECT * FROM media_files
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
+
+
+-- name: GetMediaFilesByUser :many
+SELECT * FROM media_files
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteUnattachedMediaFiles :many
+DELETE FROM media_files
+WHERE chirp_id IS NULL AND created_at < now() - interval '24 hours'
+RETURNING storage_key, thumbnail_key;
\ No newline at end of file
@@ -0,0 +1,59 @@
+-- name: SetPendingTOTPSecret :execrows
+UPDATE users
+SET totp_secret = $2, updated_at = now()
+WHERE id = $1 AND totp_enabled = false;
+
+-- name: EnableTOTP :execrows
+UPDATE users
+SET totp_enabled = true, totp_last_step = $2, updated_at = now()
+WHERE id = $1 AND totp_enabled = false AND totp_secret IS NOT NULL;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled = false, totp_last_step = 0, updated_at = now()
+WHERE id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND totp_enabled = true AND totp_last_step < $2;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash, created_at)
+VALUES (
+  $1,
+  $2,
+  now()
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (token_hash, created_at, user_id, expires_at)
+VALUES (
+  $1,
+  now(),
+  $2,
+  $3
+);
+
+-- name: AttemptMFAChallenge :one
+UPDATE mfa_challenges
+SET attempts = attempts + 1
+WHERE token_hash = sqlc.arg(token_hash) AND expires_at > sqlc.arg(now)::timestamp AND attempts < sqlc.arg(max_attempts)::int
+RETURNING user_id;
+
+-- name: DeleteMFAChallenge :exec
+DELETE FROM mfa_challenges
+WHERE token_hash = $1;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
\ No newline at end of file
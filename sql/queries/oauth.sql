@@ -0,0 +1,46 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, owner_id, name, secret_hash, redirect_uris)
+VALUES (
+  gen_random_uuid(),
+  now(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1;
+
+-- name: GetOAuthClientsByOwner :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = $1 AND owner_id = $2;
+
+-- name: CreateOAuthCode :exec
+INSERT INTO oauth_codes (code_hash, created_at, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES (
+  $1,
+  now(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7
+);
+
+-- name: ConsumeOAuthCode :one
+DELETE FROM oauth_codes
+WHERE code_hash = $1 AND client_id = $2
+RETURNING *;
+
+-- name: DeleteExpiredOAuthCodes :exec
+DELETE FROM oauth_codes
+WHERE expires_at < sqlc.arg(now)::timestamp;
\ No newline at end of file
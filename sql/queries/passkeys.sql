@@ -0,0 +1,51 @@
+-- name: CreatePasskey :one
+INSERT INTO passkeys (id, created_at, user_id, name, credential_id, public_key, sign_count)
+VALUES (
+  gen_random_uuid(),
+  now(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+RETURNING *;
+
+-- name: GetPasskeysByUser :many
+SELECT * FROM passkeys
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetPasskeyByCredentialID :one
+SELECT * FROM passkeys
+WHERE credential_id = $1;
+
+-- name: UpdatePasskeyUsage :exec
+UPDATE passkeys
+SET sign_count = $2, last_used_at = now()
+WHERE id = $1;
+
+-- name: RenamePasskey :one
+UPDATE passkeys
+SET name = $1
+WHERE id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: DeletePasskey :execrows
+DELETE FROM passkeys
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateWebAuthnChallenge :exec
+INSERT INTO webauthn_challenges (challenge_hash, created_at, user_id, ceremony, expires_at)
+VALUES (
+  $1,
+  now(),
+  $2,
+  $3,
+  $4
+);
+
+-- name: ConsumeWebAuthnChallenge :one
+DELETE FROM webauthn_challenges
+WHERE challenge_hash = sqlc.arg(challenge_hash) AND ceremony = sqlc.arg(ceremony) AND expires_at > sqlc.arg(now)::timestamp
+RETURNING user_id;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- name: PinChirp :execrows
+INSERT INTO pinned_chirps (user_id, chirp_id, created_at)
+SELECT sqlc.arg(user_id), sqlc.arg(chirp_id), now()
+WHERE (SELECT COUNT(*) FROM pinned_chirps WHERE user_id = sqlc.arg(user_id)) < sqlc.arg(max_pins)::bigint
+ON CONFLICT DO NOTHING;
+
+-- name: UnpinChirp :execrows
+DELETE FROM pinned_chirps
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetPinnedChirpIDs :many
+SELECT chirp_id FROM pinned_chirps
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+
+-- name: LockUserPins :exec
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE;
\ No newline at end of file
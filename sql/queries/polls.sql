@@ -0,0 +1,54 @@
+-- name: CreatePoll :one
+INSERT INTO polls (chirp_id, created_at, expires_at)
+VALUES (
+  $1,
+  now(),
+  $2
+)
+RETURNING *;
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (chirp_id, position, label)
+VALUES (
+  $1,
+  $2,
+  $3
+);
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+-- name: GetPollsForChirps :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollOptionsForChirps :many
+SELECT * FROM poll_options
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: GetPollTalliesForChirps :many
+SELECT chirp_id, position, COUNT(*) AS votes FROM poll_votes
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY chirp_id, position;
+
+-- name: GetUserPollVotesForChirps :many
+SELECT chirp_id, position FROM poll_votes
+WHERE user_id = sqlc.arg(user_id) AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  now()
+)
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+
+-- name: GetPollVotesByUser :many
+SELECT * FROM poll_votes
+WHERE user_id = $1
+ORDER BY created_at ASC;
\ No newline at end of file
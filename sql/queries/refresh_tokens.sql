@@ -1,20 +1,74 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, user_agent, ip_address, last_used_at, client)
 VALUES (
   $1,
   now(),
   now(),
   $2,
   $3,
-  $4
+  $4,
+  $5,
+  $6,
+  now(),
+  $7
 )
 RETURNING *;
 
 -- name: GetUserFromRefreshToken :one
-SELECT user_id FROM refresh_tokens
-WHERE token = $1 AND revoked_at IS NULL;
+SELECT user_id, client FROM refresh_tokens
+WHERE token = sqlc.arg(token) AND revoked_at IS NULL AND expires_at > sqlc.arg(now)::timestamp AND oauth_client_id IS NULL;
 
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = now(), updated_at = now()
-WHERE token = $1;
\ No newline at end of file
+WHERE token = $1;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = now(), updated_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: TouchRefreshToken :exec
+UPDATE refresh_tokens
+SET last_used_at = now(), ip_address = $2
+WHERE token = $1;
+
+-- name: GetActiveSessions :many
+SELECT id, created_at, user_agent, ip_address, client, last_used_at, expires_at FROM refresh_tokens
+WHERE user_id = sqlc.arg(user_id) AND revoked_at IS NULL AND expires_at > sqlc.arg(now)::timestamp
+ORDER BY last_used_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = now(), updated_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, user_agent, ip_address, last_used_at, oauth_client_id, scopes)
+VALUES (
+  $1,
+  now(),
+  now(),
+  $2,
+  $3,
+  $4,
+  $5,
+  now(),
+  $6,
+  $7
+)
+RETURNING *;
+
+-- name: GetOAuthRefreshToken :one
+SELECT user_id, scopes FROM refresh_tokens
+WHERE token = sqlc.arg(token) AND oauth_client_id = sqlc.arg(oauth_client_id) AND revoked_at IS NULL AND expires_at > sqlc.arg(now)::timestamp;
+
+-- name: RevokeOAuthRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = now(), updated_at = now()
+WHERE token = $1 AND oauth_client_id = $2;
+
+-- name: GetOAuthGrantsByUser :many
+SELECT id, created_at, oauth_client_id, scopes, expires_at FROM refresh_tokens
+WHERE user_id = sqlc.arg(user_id) AND oauth_client_id IS NOT NULL AND revoked_at IS NULL AND expires_at > sqlc.arg(now)::timestamp
+ORDER BY created_at;
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- name: CreateRoleChange :one
+INSERT INTO role_changes (id, created_at, user_id, changed_by, old_role, new_role)
+VALUES (
+  gen_random_uuid(),
+  now(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: GetRoleChanges :many
+SELECT * FROM role_changes
+ORDER BY created_at DESC
+LIMIT $1;
+
+-- name: GetUserRoleChanges :many
+SELECT * FROM role_changes
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: GetRoleChangesByUser :many
+SELECT * FROM role_changes
+WHERE user_id = $1
+ORDER BY created_at;
\ No newline at end of file
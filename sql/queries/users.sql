@@ -19,4 +19,61 @@ DELETE FROM users;
 -- name: ActivateChirpyRed :exec
 UPDATE users
 SET is_chirpy_red = true
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUserProtected :exec
+UPDATE users
+SET protected = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: GetProtectedAmong :many
+SELECT id FROM users
+WHERE protected = true AND id = ANY(sqlc.arg(user_ids)::uuid[]);
+
+-- name: RequestUserDeletion :one
+UPDATE users
+SET deletion_requested_at = $2, updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users
+SET deletion_requested_at = NULL, updated_at = now()
+WHERE id = $1 AND deletion_requested_at IS NOT NULL;
+
+-- name: GetUsersDueForDeletion :many
+SELECT id FROM users
+WHERE deletion_requested_at <= sqlc.arg(cutoff)::timestamp;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: VerifyUserEmail :execrows
+UPDATE users
+SET email_verified_at = now(), updated_at = now()
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: GetUserRole :one
+SELECT role FROM users
+WHERE id = $1;
+
+-- name: GetUserRoleForUpdate :one
+SELECT role FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = now()
+WHERE id = $1
+RETURNING *;
\ No newline at end of file
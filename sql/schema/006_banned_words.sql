@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE banned_words (
+  word VARCHAR PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO banned_words (word, created_at)
+VALUES ('kerfuffle', now()), ('sharbert', now()), ('fornax', now());
+
+-- +goose Down
+DROP TABLE banned_words;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE media_files (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL DEFAULT 0,
+  content_type VARCHAR NOT NULL,
+  size INTEGER NOT NULL,
+  width INTEGER NOT NULL,
+  height INTEGER NOT NULL,
+  storage_key VARCHAR NOT NULL,
+  thumbnail_key VARCHAR NOT NULL
+);
+
+CREATE INDEX media_files_chirp_id_idx ON media_files (chirp_id);
+
+-- +goose Down
+DROP TABLE media_files;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN publish_at TIMESTAMP,
+ADD COLUMN published BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE chirps SET publish_at = created_at;
+
+ALTER TABLE chirps
+ALTER COLUMN publish_at SET NOT NULL;
+
+CREATE INDEX chirps_scheduled_idx ON chirps (publish_at) WHERE published = false;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN publish_at,
+DROP COLUMN published;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE drafts (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  body VARCHAR NOT NULL
+);
+
+-- +goose Down
+DROP TABLE drafts;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE bookmark_collections (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR NOT NULL,
+  UNIQUE (user_id, name)
+);
+
+CREATE TABLE bookmarks (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  collection_id UUID REFERENCES bookmark_collections(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX bookmarks_unsorted_idx ON bookmarks (user_id, chirp_id) WHERE collection_id IS NULL;
+CREATE UNIQUE INDEX bookmarks_collection_idx ON bookmarks (collection_id, chirp_id) WHERE collection_id IS NOT NULL;
+
+-- +goose Down
+DROP TABLE bookmarks;
+DROP TABLE bookmark_collections;
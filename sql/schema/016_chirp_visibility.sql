@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN visibility VARCHAR NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'followers', 'mentioned'));
+
+CREATE TABLE chirp_mentions (
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
+
+ALTER TABLE chirps
+DROP COLUMN visibility;
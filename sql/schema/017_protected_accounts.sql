@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN protected BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE follows
+ADD COLUMN approved BOOLEAN NOT NULL DEFAULT true;
+
+-- +goose Down
+ALTER TABLE follows
+DROP COLUMN approved;
+
+ALTER TABLE users
+DROP COLUMN protected;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE lists (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR NOT NULL,
+  private BOOLEAN NOT NULL DEFAULT false,
+  UNIQUE (user_id, name)
+);
+
+CREATE TABLE list_members (
+  list_id UUID NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (list_id, user_id)
+);
+
+CREATE INDEX chirps_timeline_idx ON chirps (user_id, publish_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX chirps_timeline_idx;
+DROP TABLE list_members;
+DROP TABLE lists;
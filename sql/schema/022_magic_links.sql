@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE magic_links (
+  token_hash VARCHAR PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  device_hash VARCHAR NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE magic_links;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE passkeys (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR NOT NULL,
+  credential_id BYTEA NOT NULL UNIQUE,
+  public_key BYTEA NOT NULL,
+  sign_count BIGINT NOT NULL,
+  last_used_at TIMESTAMP
+);
+
+CREATE INDEX passkeys_user_id_idx ON passkeys (user_id);
+
+CREATE TABLE webauthn_challenges (
+  challenge_hash VARCHAR PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  ceremony VARCHAR NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE webauthn_challenges;
+DROP TABLE passkeys;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD COLUMN id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+ADD COLUMN user_agent VARCHAR NOT NULL DEFAULT '',
+ADD COLUMN ip_address VARCHAR NOT NULL DEFAULT '',
+ADD COLUMN last_used_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN last_used_at,
+DROP COLUMN ip_address,
+DROP COLUMN user_agent,
+DROP COLUMN id;
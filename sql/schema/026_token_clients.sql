@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD COLUMN client VARCHAR NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN client;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role VARCHAR NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
+
+CREATE TABLE role_changes (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  old_role VARCHAR NOT NULL,
+  new_role VARCHAR NOT NULL
+);
+
+CREATE INDEX role_changes_created_at_idx ON role_changes (created_at DESC);
+
+-- +goose Down
+DROP TABLE role_changes;
+
+ALTER TABLE users
+DROP COLUMN role;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR NOT NULL,
+  token_hash VARCHAR NOT NULL UNIQUE,
+  scopes VARCHAR NOT NULL,
+  expires_at TIMESTAMP,
+  last_used_at TIMESTAMP
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
+
+-- +goose Down
+DROP TABLE api_tokens;
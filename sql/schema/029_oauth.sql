@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE oauth_clients (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR NOT NULL,
+  secret_hash VARCHAR,
+  redirect_uris VARCHAR NOT NULL
+);
+
+CREATE TABLE oauth_codes (
+  code_hash VARCHAR PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri VARCHAR NOT NULL,
+  scopes VARCHAR NOT NULL,
+  code_challenge VARCHAR NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE refresh_tokens
+ADD COLUMN oauth_client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE,
+ADD COLUMN scopes VARCHAR NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN scopes,
+DROP COLUMN oauth_client_id;
+
+DROP TABLE oauth_codes;
+DROP TABLE oauth_clients;
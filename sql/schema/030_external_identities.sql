@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE external_identities (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider VARCHAR NOT NULL,
+  subject VARCHAR NOT NULL,
+  email VARCHAR NOT NULL,
+  UNIQUE (provider, subject)
+);
+
+CREATE TABLE oidc_states (
+  state_hash VARCHAR PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  provider VARCHAR NOT NULL,
+  nonce VARCHAR NOT NULL,
+  code_verifier VARCHAR NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE oidc_states;
+DROP TABLE external_identities;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Failed logins are counted per email address rather than per account, so
+-- that unknown addresses are throttled exactly like existing ones.
+CREATE TABLE login_email_failures (
+  email VARCHAR PRIMARY KEY,
+  last_failed_at TIMESTAMP NOT NULL,
+  failures INTEGER NOT NULL,
+  locked_until TIMESTAMP
+);
+
+CREATE TABLE login_ip_failures (
+  ip_address VARCHAR PRIMARY KEY,
+  window_started_at TIMESTAMP NOT NULL,
+  failures INTEGER NOT NULL,
+  locked_until TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE login_ip_failures;
+
+DROP TABLE login_email_failures;
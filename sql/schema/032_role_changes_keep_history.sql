@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE role_changes
+ALTER COLUMN user_id DROP NOT NULL,
+DROP CONSTRAINT role_changes_user_id_fkey,
+ADD CONSTRAINT role_changes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM role_changes WHERE user_id IS NULL;
+
+ALTER TABLE role_changes
+ALTER COLUMN user_id SET NOT NULL,
+DROP CONSTRAINT role_changes_user_id_fkey,
+ADD CONSTRAINT role_changes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dipzza/bootdev_chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTimelineLimit = 20
+	maxTimelineLimit     = 100
+)
+
+// Timeline is one page of chirps, newest first. NextCursor is nil on the
+// last page.
+type Timeline struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// timelineCursor points just past the last chirp of a page. Chirps are
+// ordered by (publish_at, id) so that chirps published in the same instant
+// are neither repeated nor skipped.
+type timelineCursor struct {
+	PublishAt time.Time
+	ID        uuid.UUID
+}
+
+// firstPage sorts after every chirp.
+var firstPage = timelineCursor{
+	PublishAt: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC),
+	ID:        uuid.Max,
+}
+
+func (c timelineCursor) String() string {
+	raw := c.PublishAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseTimelineCursor(s string) (timelineCursor, error) {
+	invalid := errors.New("Invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return timelineCursor{}, invalid
+	}
+	publishAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return timelineCursor{}, invalid
+	}
+
+	cursor := timelineCursor{}
+	if cursor.PublishAt, err = time.Parse(time.RFC3339Nano, publishAt); err != nil {
+		return timelineCursor{}, invalid
+	}
+	if cursor.ID, err = uuid.Parse(id); err != nil {
+		return timelineCursor{}, invalid
+	}
+	return cursor, nil
+}
+
+// timelineParams reads the cursor and limit query parameters.
+func timelineParams(r *http.Request) (timelineCursor, int, error) {
+	cursor := firstPage
+	if param := r.URL.Query().Get("cursor"); param != "" {
+		var err error
+		if cursor, err = parseTimelineCursor(param); err != nil {
+			return timelineCursor{}, 0, err
+		}
+	}
+
+	limit := defaultTimelineLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		var err error
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxTimelineLimit {
+			return timelineCursor{}, 0, errors.New("limit must be a number between 1 and " + strconv.Itoa(maxTimelineLimit))
+		}
+	}
+
+	return cursor, limit, nil
+}
+
+// timeline returns the page of chirps by authorIDs that comes after cursor,
+// as seen by viewer. Chirps the viewer can't see are skipped without
+// shortening the page, fetching further back as needed.
+func (cfg *apiConfig) timeline(ctx context.Context, authorIDs []uuid.UUID, viewer uuid.UUID, cursor timelineCursor, limit int) (Timeline, error) {
+	page := []database.Chirp{}
+	exhausted := false
+	for len(page) < limit && !exhausted {
+		batch, err := cfg.db.GetTimelineChirps(ctx, database.GetTimelineChirpsParams{
+			UserIds:         authorIDs,
+			BeforePublishAt: cursor.PublishAt,
+			BeforeID:        cursor.ID,
+			PageSize:        int32(limit),
+		})
+		if err != nil {
+			return Timeline{}, err
+		}
+		exhausted = len(batch) < limit
+
+		visible, err := cfg.visibleChirps(ctx, batch, viewer)
+		if err != nil {
+			return Timeline{}, err
+		}
+		visibleIDs := make(map[uuid.UUID]bool, len(visible))
+		for _, chirp := range visible {
+			visibleIDs[chirp.ID] = true
+		}
+
+		for i, chirp := range batch {
+			cursor = timelineCursor{PublishAt: chirp.PublishAt, ID: chirp.ID}
+			if visibleIDs[chirp.ID] {
+				page = append(page, chirp)
+			}
+			if len(page) == limit {
+				exhausted = exhausted && i == len(batch)-1
+				break
+			}
+		}
+	}
+
+	chirps, err := cfg.chirpsResponse(ctx, page, viewer)
+	if err != nil {
+		return Timeline{}, err
+	}
+	response := Timeline{Chirps: chirps}
+	if !exhausted {
+		next := cursor.String()
+		response.NextCursor = &next
+	}
+	return response, nil
+}
+
+func (cfg *apiConfig) handlerHomeTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticatedUser(r)
+	if err != nil {
+		respondUnauthorized(w, err)
+		return
+	}
+
+	cursor, limit, err := timelineParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	followees, err := cfg.db.GetFollowees(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response, err := cfg.timeline(r.Context(), append(followees, userID), userID, cursor, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}